@@ -0,0 +1,104 @@
+//go:build linux
+
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var systemdSockets struct {
+	once      sync.Once
+	mu        sync.Mutex
+	listeners []*namedListener
+	err       error
+}
+
+// systemdListener returns a listener passed through LISTEN_FDS/LISTEN_PID.
+// The environment is read once; each listener can be claimed by one server.
+func systemdListener(name string) (net.Listener, error) {
+	systemdSockets.once.Do(func() {
+		systemdSockets.listeners, systemdSockets.err = activatedListeners()
+	})
+	if systemdSockets.err != nil {
+		return nil, systemdSockets.err
+	}
+
+	systemdSockets.mu.Lock()
+	defer systemdSockets.mu.Unlock()
+
+	return claimListener(systemdSockets.listeners, name)
+}
+
+// activatedListeners implements the sd_listen_fds(3) protocol and unsets
+// the variables so child processes don't inherit them.
+func activatedListeners() ([]*namedListener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("no systemd socket-activated listeners for this process")
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS [%s]", os.Getenv("LISTEN_FDS"))
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make([]*namedListener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := listenFDsStart + i
+		syscall.CloseOnExec(fd)
+
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(fd), "systemd:"+name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("creating listener from fd %d: %w", fd, err)
+		}
+
+		listeners = append(listeners, &namedListener{name: name, listener: ln})
+	}
+
+	return listeners, nil
+}
+
+// sdNotify implements the sd_notify(3) protocol. It is a no-op when the
+// process was not started by systemd with Type=notify.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// A leading "@" denotes a socket in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("dialing notify socket [%s]: %w", socket, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("writing notify state [%s]: %w", state, err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package http
+
+import (
+	"fmt"
+	"net"
+)
+
+// systemdListener is only supported on linux.
+func systemdListener(string) (net.Listener, error) {
+	return nil, fmt.Errorf("systemd socket activation is only supported on linux")
+}
+
+// sdNotify is a no-op since systemd only runs on linux.
+func sdNotify(string) error {
+	return nil
+}
@@ -0,0 +1,310 @@
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings supported by Compress
+const (
+	EncodingZstd    = "zstd"
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressionPolicy configures response compression.
+type CompressionPolicy struct {
+	// Encodings are the codings offered, preferred in order when the client
+	// accepts several with the same quality.
+	Encodings []string
+	// MinSize is the response size in bytes below which responses are sent
+	// uncompressed.
+	MinSize int
+	// ContentTypes lists the media types compressed. "text/*" matches any
+	// text type.
+	ContentTypes []string
+}
+
+// compressor is implemented by the writers of every supported coding.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressors pools a writer per coding; they are expensive to allocate.
+var compressors = map[string]*sync.Pool{
+	EncodingZstd: {New: func() any {
+		// Each writer serves one response at a time
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}},
+	EncodingBrotli: {New: func() any {
+		return brotli.NewWriterLevel(nil, 5)
+	}},
+	EncodingGzip: {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	EncodingDeflate: {New: func() any {
+		return zlib.NewWriter(nil)
+	}},
+}
+
+// Compress compresses responses with the best coding accepted by the client
+// through Accept-Encoding. Responses are buffered up to MinSize to decide;
+// smaller ones, responses with a media type not in ContentTypes, partial
+// content and responses already encoded are sent as is. A flush before
+// MinSize is reached starts compressing right away so streams keep flowing.
+func Compress(policy CompressionPolicy) (Middleware, error) {
+	for _, enc := range policy.Encodings {
+		if _, ok := compressors[enc]; !ok {
+			return nil, fmt.Errorf("unknown compression encoding [%s]", enc)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), policy.Encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				if r.Method != http.MethodHead {
+					w.Header().Add("Vary", "Accept-Encoding")
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Not deferred: after a panic the buffered response is dropped so
+			// Recover can still send a 500
+			cw := &compressWriter{ResponseWriter: w, policy: &policy, encoding: encoding}
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}, nil
+}
+
+// negotiateEncoding returns the coding of supported with the highest
+// quality in the Accept-Encoding header, or "" when none is acceptable.
+func negotiateEncoding(header string, supported []string) string {
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		if q := encodingQuality(header, enc); q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// encodingQuality returns the q-value the Accept-Encoding header gives
+// encoding, 0 when it isn't accepted.
+func encodingQuality(header, encoding string) float64 {
+	wildcard := 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if strings.EqualFold(name, encoding) {
+			return q
+		}
+		if name == "*" {
+			wildcard = q
+		}
+	}
+	return wildcard
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	policy   *CompressionPolicy
+	encoding string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	compressor  compressor
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	// Informational responses may precede the final status
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.wroteHeader {
+		return
+	}
+	cw.status = code
+	cw.wroteHeader = true
+
+	if !cw.eligible() {
+		cw.start(false)
+		return
+	}
+	// Compressible types vary with Accept-Encoding even when small
+	cw.Header().Add("Vary", "Accept-Encoding")
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.compressor != nil {
+			return cw.compressor.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.policy.MinSize {
+		if err := cw.start(cw.eligible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher. A flush before the decision starts
+// compressing when the response is eligible.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		_ = cw.start(cw.eligible())
+	}
+	if cw.compressor != nil {
+		_ = cw.compressor.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker so WebSocket upgrades keep working.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// eligible reports whether the response may be compressed, sniffing its
+// content type from the buffered body if the handler set none.
+func (cw *compressWriter) eligible() bool {
+	h := cw.Header()
+	switch {
+	case cw.status < 200, cw.status == http.StatusNoContent, cw.status == http.StatusNotModified,
+		cw.status == http.StatusPartialContent, h.Get("Content-Encoding") != "", h.Get("Content-Range") != "":
+		return false
+	}
+
+	if cl, err := strconv.Atoi(h.Get("Content-Length")); err == nil && cl < cw.policy.MinSize {
+		return false
+	}
+
+	ctype := h.Get("Content-Type")
+	if ctype == "" {
+		if len(cw.buf) == 0 {
+			// Decided on the body once it is written
+			return true
+		}
+		ctype = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", ctype)
+	}
+	return cw.policy.allowsType(ctype)
+}
+
+// start sends the header and the buffered body, compressed or not.
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+
+	if compress {
+		h := cw.Header()
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		// A strong ETag no longer matches the encoded bytes
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+
+		cw.compressor = compressors[cw.encoding].Get().(compressor)
+		cw.compressor.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// close sends what is still buffered and finishes the compressed stream.
+func (cw *compressWriter) close() {
+	if !cw.wroteHeader {
+		// The handler wrote nothing; let net/http send its default response
+		return
+	}
+	if !cw.decided {
+		// Smaller than MinSize
+		_ = cw.start(false)
+	}
+	if cw.compressor != nil {
+		_ = cw.compressor.Close()
+		cw.compressor.Reset(io.Discard)
+		compressors[cw.encoding].Put(cw.compressor)
+		cw.compressor = nil
+	}
+}
+
+func (p *CompressionPolicy) allowsType(ctype string) bool {
+	mediaType, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range p.ContentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
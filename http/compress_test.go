@@ -0,0 +1,200 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func testCompression(t *testing.T) Middleware {
+	t.Helper()
+	mw, err := Compress(CompressionPolicy{
+		Encodings:    []string{EncodingZstd, EncodingBrotli, EncodingGzip, EncodingDeflate},
+		MinSize:      64,
+		ContentTypes: []string{"text/*", "application/json"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	return mw
+}
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	var err error
+	switch encoding {
+	case EncodingZstd:
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(bytes.NewReader(body))
+		if err == nil {
+			defer d.Close()
+		}
+		r = d
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingGzip:
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case EncodingDeflate:
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	if err != nil {
+		t.Fatalf("failed to create %s reader: %v", encoding, err)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decompress %s body: %v", encoding, err)
+	}
+	return string(out)
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("a", 512) + `"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantEncoding   string
+		wantVary       bool
+	}{
+		{name: "zstd preferred", acceptEncoding: "gzip, deflate, br, zstd", contentType: "application/json", body: large, wantEncoding: "zstd", wantVary: true},
+		{name: "quality wins", acceptEncoding: "zstd;q=0.5, gzip", contentType: "application/json", body: large, wantEncoding: "gzip", wantVary: true},
+		{name: "brotli", acceptEncoding: "br", contentType: "text/html; charset=utf-8", body: large, wantEncoding: "br", wantVary: true},
+		{name: "deflate", acceptEncoding: "deflate", contentType: "application/json", body: large, wantEncoding: "deflate", wantVary: true},
+		{name: "sniffed type", acceptEncoding: "gzip", body: strings.Repeat("plain text ", 20), wantEncoding: "gzip", wantVary: true},
+		{name: "below min size", acceptEncoding: "gzip", contentType: "application/json", body: `{"ok":true}`, wantVary: true},
+		{name: "type not allowed", acceptEncoding: "gzip", contentType: "image/png", body: large},
+		{name: "not accepted", acceptEncoding: "identity", contentType: "application/json", body: large, wantVary: true},
+		{name: "refused", acceptEncoding: "gzip;q=0", contentType: "application/json", body: large, wantVary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testCompression(t)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				// Write in chunks to cross the threshold mid-response
+				for i := 0; i < len(tt.body); i += 100 {
+					_, _ = io.WriteString(w, tt.body[i:min(i+100, len(tt.body))])
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("expected content encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Errorf("expected Vary: Accept-Encoding %v, got %q", tt.wantVary, rec.Header().Get("Vary"))
+			}
+			if body := decompress(t, tt.wantEncoding, rec.Body.Bytes()); body != tt.body {
+				t.Errorf("expected body to round-trip, got %q", body)
+			}
+		})
+	}
+}
+
+func TestCompress_Flush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	h := testCompression(t)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: 1\n\n")
+		http.NewResponseController(w).Flush()
+
+		// The event must reach the client before the handler returns
+		if rec.Body.Len() == 0 {
+			t.Error("expected flush to send the buffered event")
+		}
+		_, _ = io.WriteString(w, "data: 2\n\n")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Error("expected flush to pass through")
+	}
+	if got := decompress(t, rec.Header().Get("Content-Encoding"), rec.Body.Bytes()); got != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("expected both events, got %q", got)
+	}
+}
+
+func TestCompress_Skipped(t *testing.T) {
+	large := strings.Repeat("a", 512)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "already encoded", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = io.WriteString(w, large)
+		}},
+		{name: "partial content", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = io.WriteString(w, large)
+		}},
+		{name: "small content length", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Length", "2")
+			_, _ = io.WriteString(w, "ok")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			testCompression(t)(tt.handler).ServeHTTP(rec, req)
+
+			if rec.Header().Get("Content-Encoding") == "gzip" {
+				t.Error("expected response not to be compressed")
+			}
+		})
+	}
+}
+
+func TestCompress_Panic(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, "partial")
+		panic("boom")
+	}), Recover(testLogger()), testCompression(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 after a panic, got %d", rec.Code)
+	}
+}
+
+func TestCompress_UnknownEncoding(t *testing.T) {
+	if _, err := Compress(CompressionPolicy{Encodings: []string{"lzma"}}); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+}
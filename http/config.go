@@ -1,19 +1,102 @@
 package http
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/ardanlabs/conf/v3"
 )
 
+// Config holds the server settings. Keys in config files are the env names
+// in lower case. Fields tagged reload:"restart" are only applied on restart;
+// the others can be changed at runtime with Server.ApplyConfig.
 type Config struct {
-	Address           string        `conf:"env:ADDRESS,default:0.0.0.0:3000"`
-	ReadHeaderTimeout time.Duration `conf:"env:READ_HEADER_TIMEOUT,default:60s"`
-	ReadTimeout       time.Duration `conf:"env:READ_TIMEOUT,default:10s"`
-	WriteTimeout      time.Duration `conf:"env:WRITE_TIMEOUT,default:10s"`
-	IdleTimeout       time.Duration `conf:"env:IDLE_TIMEOUT,default:60s"`
-	ShutdownTimeout   time.Duration `conf:"env:SHUTDOWN_TIMEOUT,default:20s"`
+	Address           string        `conf:"env:ADDRESS,default:0.0.0.0:3000" yaml:"address" reload:"restart"`
+	ReadHeaderTimeout time.Duration `conf:"env:READ_HEADER_TIMEOUT,default:60s" yaml:"read_header_timeout" reload:"restart"`
+	ReadTimeout       time.Duration `conf:"env:READ_TIMEOUT,default:10s" yaml:"read_timeout"`
+	WriteTimeout      time.Duration `conf:"env:WRITE_TIMEOUT,default:10s" yaml:"write_timeout"`
+	IdleTimeout       time.Duration `conf:"env:IDLE_TIMEOUT,default:60s" yaml:"idle_timeout" reload:"restart"`
+	ShutdownTimeout   time.Duration `conf:"env:SHUTDOWN_TIMEOUT,default:20s" yaml:"shutdown_timeout"`
+
+	// How long the server keeps serving with readiness failing before it
+	// starts draining on shutdown, so load balancers can stop routing to it
+	ShutdownDelay time.Duration `conf:"env:SHUTDOWN_DELAY,default:0s" yaml:"shutdown_delay"`
+
+	// Serve /livez, /readyz and /healthz from the server's health registry;
+	// off by default so they don't shadow routes of the handler
+	HealthEndpoints bool `conf:"env:HEALTH_ENDPOINTS,default:false" yaml:"health_endpoints"`
+
+	// Standard middleware; opt-in so upgrading doesn't change the behavior
+	// of existing servers
+	RequestID     bool `conf:"env:REQUEST_ID,default:false" yaml:"request_id"`
+	AccessLog     bool `conf:"env:ACCESS_LOG,default:false" yaml:"access_log"`
+	RecoverPanics bool `conf:"env:RECOVER_PANICS,default:false" yaml:"recover_panics"`
+
+	// Record Prometheus request metrics in the default registry
+	Metrics bool `conf:"env:METRICS,default:false" yaml:"metrics"`
+
+	// Start an OpenTelemetry span per request using the global tracer
+	// provider; a no-op until an SDK is installed
+	Tracing bool `conf:"env:TRACING,default:false" yaml:"tracing"`
+
+	// Minimum level of the server's logs, including access logs ("debug",
+	// "info", "warn" or "error"); empty keeps the logger's own level
+	LogLevel string `conf:"env:LOG_LEVEL" yaml:"log_level"`
+
+	// Per-client rate limiting; disabled when RateLimitRPS is 0. The key is
+	// "ip" or "header:<name>".
+	RateLimitRPS            float64 `conf:"env:RATE_LIMIT_RPS,default:0" yaml:"rate_limit_rps"`
+	RateLimitBurst          int     `conf:"env:RATE_LIMIT_BURST,default:0" yaml:"rate_limit_burst"`
+	RateLimitKey            string  `conf:"env:RATE_LIMIT_KEY,default:ip" yaml:"rate_limit_key"`
+	RateLimitTrustForwarded bool    `conf:"env:RATE_LIMIT_TRUST_FORWARDED,default:false" yaml:"rate_limit_trust_forwarded"`
+
+	// CORS policy; disabled when no origins are allowed. Lists are
+	// ";"-separated.
+	CORSAllowedOrigins   []string      `conf:"env:CORS_ALLOWED_ORIGINS" yaml:"cors_allowed_origins"`
+	CORSAllowedMethods   []string      `conf:"env:CORS_ALLOWED_METHODS,default:GET;HEAD;POST;PUT;PATCH;DELETE" yaml:"cors_allowed_methods"`
+	CORSAllowedHeaders   []string      `conf:"env:CORS_ALLOWED_HEADERS,default:Accept;Authorization;Content-Type;X-Request-ID" yaml:"cors_allowed_headers"`
+	CORSExposedHeaders   []string      `conf:"env:CORS_EXPOSED_HEADERS,default:X-Request-ID" yaml:"cors_exposed_headers"`
+	CORSAllowCredentials bool          `conf:"env:CORS_ALLOW_CREDENTIALS,default:false" yaml:"cors_allow_credentials"`
+	CORSMaxAge           time.Duration `conf:"env:CORS_MAX_AGE,default:10m" yaml:"cors_max_age"`
+
+	// Security headers profile ("basic" or "strict"; empty disables)
+	SecurityHeaders       string        `conf:"env:SECURITY_HEADERS" yaml:"security_headers"`
+	ContentSecurityPolicy string        `conf:"env:CONTENT_SECURITY_POLICY" yaml:"content_security_policy"`
+	HSTSMaxAge            time.Duration `conf:"env:HSTS_MAX_AGE,default:0s" yaml:"hsts_max_age"`
+
+	// Response compression. Encodings ("zstd", "br", "gzip", "deflate") are
+	// preferred in order; responses smaller than CompressionMinSize bytes or
+	// of other content types are sent as is.
+	Compression             bool     `conf:"env:COMPRESSION,default:false" yaml:"compression"`
+	CompressionEncodings    []string `conf:"env:COMPRESSION_ENCODINGS,default:zstd;br;gzip;deflate" yaml:"compression_encodings"`
+	CompressionMinSize      int      `conf:"env:COMPRESSION_MIN_SIZE,default:1024" yaml:"compression_min_size"`
+	CompressionContentTypes []string `conf:"env:COMPRESSION_CONTENT_TYPES,default:text/*;application/json;application/problem+json;application/javascript;application/xml;image/svg+xml" yaml:"compression_content_types"`
+
+	// Maximum request body size in bytes; 0 disables the limit
+	MaxRequestBodyBytes int64 `conf:"env:MAX_REQUEST_BODY_BYTES,default:0" yaml:"max_request_body_bytes"`
+
+	// Global in-flight request limit; disabled when MaxInFlight is 0
+	MaxInFlight             int           `conf:"env:MAX_IN_FLIGHT,default:0" yaml:"max_in_flight"`
+	MaxInFlightQueueTimeout time.Duration `conf:"env:MAX_IN_FLIGHT_QUEUE_TIMEOUT,default:100ms" yaml:"max_in_flight_queue_timeout"`
+
+	// TLS settings; TLS is enabled when both cert and key files are set
+	TLSCertFile       string        `conf:"env:TLS_CERT_FILE" yaml:"tls_cert_file" reload:"restart"`
+	TLSKeyFile        string        `conf:"env:TLS_KEY_FILE" yaml:"tls_key_file" reload:"restart"`
+	TLSClientCAFile   string        `conf:"env:TLS_CLIENT_CA_FILE" yaml:"tls_client_ca_file" reload:"restart"`
+	TLSMinVersion     string        `conf:"env:TLS_MIN_VERSION,default:1.2" yaml:"tls_min_version" reload:"restart"`
+	TLSCipherSuites   []string      `conf:"env:TLS_CIPHER_SUITES" yaml:"tls_cipher_suites" reload:"restart"`
+	TLSReloadInterval time.Duration `conf:"env:TLS_RELOAD_INTERVAL,default:1m" yaml:"tls_reload_interval" reload:"restart"`
+
+	// Protocol settings. H2C enables HTTP/2 with prior knowledge on plaintext
+	// listeners; HTTP3 requires TLS and listens on UDP at HTTP3Address or the
+	// TCP listener's address.
+	H2C                       bool   `conf:"env:H2C,default:false" yaml:"h2c" reload:"restart"`
+	HTTP2MaxConcurrentStreams int    `conf:"env:HTTP2_MAX_CONCURRENT_STREAMS,default:0" yaml:"http2_max_concurrent_streams" reload:"restart"`
+	HTTP2MaxHeaderListSize    int    `conf:"env:HTTP2_MAX_HEADER_LIST_SIZE,default:0" yaml:"http2_max_header_list_size" reload:"restart"`
+	HTTP3                     bool   `conf:"env:HTTP3,default:false" yaml:"http3" reload:"restart"`
+	HTTP3Address              string `conf:"env:HTTP3_ADDRESS" yaml:"http3_address" reload:"restart"`
 }
 
 func LoadConfig(prefix string) (Config, error) {
@@ -26,3 +109,67 @@ func LoadConfig(prefix string) (Config, error) {
 
 	return cfg, nil
 }
+
+// validate reports every runtime setting of c that its middleware or the
+// logger would reject.
+func (c Config) validate() error {
+	var errs []error
+	if c.LogLevel != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			errs = append(errs, fmt.Errorf("invalid log level [%s]", c.LogLevel))
+		}
+	}
+	if c.Compression {
+		if _, err := Compress(compressionPolicy(c)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if securityHeadersEnabled(c) {
+		if _, err := SecurityHeaders(securityHeadersPolicy(c)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(c.CORSAllowedOrigins) > 0 {
+		if _, err := CORS(corsPolicy(c)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.RateLimitRPS > 0 {
+		if _, err := ParseKeyFunc(c.RateLimitKey, c.RateLimitTrustForwarded); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func compressionPolicy(c Config) CompressionPolicy {
+	return CompressionPolicy{
+		Encodings:    c.CompressionEncodings,
+		MinSize:      c.CompressionMinSize,
+		ContentTypes: c.CompressionContentTypes,
+	}
+}
+
+func securityHeadersEnabled(c Config) bool {
+	return c.SecurityHeaders != "" || c.ContentSecurityPolicy != "" || c.HSTSMaxAge > 0
+}
+
+func securityHeadersPolicy(c Config) SecurityHeadersPolicy {
+	return SecurityHeadersPolicy{
+		Profile:               c.SecurityHeaders,
+		ContentSecurityPolicy: c.ContentSecurityPolicy,
+		HSTSMaxAge:            c.HSTSMaxAge,
+	}
+}
+
+func corsPolicy(c Config) CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins:   c.CORSAllowedOrigins,
+		AllowedMethods:   c.CORSAllowedMethods,
+		AllowedHeaders:   c.CORSAllowedHeaders,
+		ExposedHeaders:   c.CORSExposedHeaders,
+		AllowCredentials: c.CORSAllowCredentials,
+		MaxAge:           c.CORSMaxAge,
+	}
+}
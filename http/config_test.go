@@ -0,0 +1,53 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, err := LoadConfig("GOXTEST")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if cfg.Address != "0.0.0.0:3000" {
+		t.Errorf("expected default address, got %s", cfg.Address)
+	}
+	if cfg.ShutdownTimeout != 20*time.Second {
+		t.Errorf("expected default shutdown timeout 20s, got %v", cfg.ShutdownTimeout)
+	}
+	if cfg.HealthEndpoints || cfg.RequestID || cfg.AccessLog || cfg.RecoverPanics || cfg.Metrics || cfg.Tracing {
+		t.Errorf("expected health endpoints, standard middleware, metrics and tracing to be opt-in, got %+v", cfg)
+	}
+	if len(cfg.CORSAllowedMethods) != 6 || cfg.CORSAllowedMethods[0] != "GET" {
+		t.Errorf("expected default CORS methods, got %v", cfg.CORSAllowedMethods)
+	}
+	if cfg.Compression || len(cfg.CompressionEncodings) != 4 || cfg.CompressionEncodings[0] != EncodingZstd {
+		t.Errorf("expected compression off with default encodings, got %v %v", cfg.Compression, cfg.CompressionEncodings)
+	}
+	if _, err := Compress(CompressionPolicy{Encodings: cfg.CompressionEncodings}); err != nil {
+		t.Errorf("expected default encodings to be supported: %v", err)
+	}
+}
+
+func TestLoadConfig_Env(t *testing.T) {
+	t.Setenv("GOXTEST_CORS_ALLOWED_ORIGINS", "https://a.example.com;https://*.example.org")
+	t.Setenv("GOXTEST_MAX_REQUEST_BODY_BYTES", "1048576")
+	t.Setenv("GOXTEST_RATE_LIMIT_RPS", "2.5")
+
+	cfg, err := LoadConfig("GOXTEST")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(cfg.CORSAllowedOrigins) != 2 || cfg.CORSAllowedOrigins[1] != "https://*.example.org" {
+		t.Errorf("expected two CORS origins, got %v", cfg.CORSAllowedOrigins)
+	}
+	if cfg.MaxRequestBodyBytes != 1<<20 {
+		t.Errorf("expected max body 1MiB, got %d", cfg.MaxRequestBodyBytes)
+	}
+	if cfg.RateLimitRPS != 2.5 {
+		t.Errorf("expected rate limit 2.5, got %v", cfg.RateLimitRPS)
+	}
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	policy := CORSPolicy{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name        string
+		policy      CORSPolicy
+		method      string
+		origin      string
+		preflight   bool
+		wantOrigin  string
+		wantStatus  int
+		wantHandler bool
+	}{
+		{name: "exact origin", policy: policy, method: "GET", origin: "https://app.example.com", wantOrigin: "https://app.example.com", wantStatus: 200, wantHandler: true},
+		{name: "wildcard subdomain", policy: policy, method: "GET", origin: "https://api.example.org", wantOrigin: "https://api.example.org", wantStatus: 200, wantHandler: true},
+		{name: "wildcard does not match apex", policy: policy, method: "GET", origin: "https://example.org", wantStatus: 200, wantHandler: true},
+		{name: "disallowed origin", policy: policy, method: "GET", origin: "https://evil.com", wantStatus: 200, wantHandler: true},
+		{name: "preflight", policy: policy, method: "OPTIONS", origin: "https://app.example.com", preflight: true, wantOrigin: "https://app.example.com", wantStatus: 204},
+		{name: "preflight disallowed", policy: policy, method: "OPTIONS", origin: "https://evil.com", preflight: true, wantStatus: 204},
+		{name: "any origin", policy: CORSPolicy{AllowedOrigins: []string{"*"}}, method: "GET", origin: "https://x.io", wantOrigin: "*", wantStatus: 200, wantHandler: true},
+		{name: "subdomain wildcard with credentials", policy: CORSPolicy{AllowedOrigins: []string{"https://*.x.io"}, AllowCredentials: true}, method: "GET", origin: "https://app.x.io", wantOrigin: "https://app.x.io", wantStatus: 200, wantHandler: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cors, err := CORS(tt.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			called := false
+			h := cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if called != tt.wantHandler {
+				t.Errorf("expected handler called=%v, got %v", tt.wantHandler, called)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.wantOrigin, got)
+			}
+			if rec.Header().Get("Vary") == "" {
+				t.Error("expected Vary header")
+			}
+		})
+	}
+}
+
+func TestCORS_AnyOriginWithCredentials(t *testing.T) {
+	_, err := CORS(CORSPolicy{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true})
+	if err == nil {
+		t.Fatal("expected error for any origin with credentials")
+	}
+}
+
+func TestCORS_PreflightHeaders(t *testing.T) {
+	cors, err := CORS(CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := cors(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	want := map[string]string{
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("expected %s %q, got %q", k, v, got)
+		}
+	}
+}
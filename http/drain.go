@@ -0,0 +1,206 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// drainPollInterval is how often Shutdown checks whether in-flight requests
+// and hijacked connections have finished.
+const drainPollInterval = 10 * time.Millisecond
+
+type drainingKey struct{}
+
+// Draining returns a channel that is closed when the server handling the
+// request starts draining on shutdown. http.Server.Shutdown neither
+// interrupts long-poll and SSE responses nor tracks hijacked (WebSocket)
+// connections, so such handlers should select on it to finish the response
+// or close the connection cleanly. Outside a Server it returns nil.
+func Draining(ctx context.Context) <-chan struct{} {
+	drain, ok := ctx.Value(drainingKey{}).(context.Context)
+	if !ok {
+		return nil
+	}
+	return drain.Done()
+}
+
+// DrainContext returns a copy of ctx that is also cancelled when the server
+// handling the request starts draining, for code that takes a context
+// rather than selecting on Draining. Outside a Server it only follows ctx.
+// Call cancel once the work is done to release its resources.
+func DrainContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	drain, ok := ctx.Value(drainingKey{}).(context.Context)
+	ctx, cancel := context.WithCancel(ctx)
+	if !ok {
+		return ctx, cancel
+	}
+
+	stop := context.AfterFunc(drain, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// InFlight returns the number of requests being handled, including the
+// handlers of hijacked connections.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// Shutdown gracefully shuts down the server. Readiness fails first and the
+// server keeps serving for ShutdownDelay, so load balancers stop routing to
+// it before connections are refused. The server then stops accepting
+// connections, closes the Draining channel of in-flight requests and waits
+// for them and for hijacked connections to finish. If ctx is done first,
+// the remaining connections are closed and the context error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.SetShuttingDown()
+	waitShutdownDelay(ctx, s.logger, s.Config().ShutdownDelay)
+	return s.drain(ctx)
+}
+
+// waitShutdownDelay waits for delay while readiness fails, or until ctx is
+// done.
+func waitShutdownDelay(ctx context.Context, logger *slog.Logger, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+
+	logger.Info("readiness failing, waiting before draining",
+		slog.Duration("delay", delay),
+	)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// drain stops accepting connections, notifies in-flight requests and waits
+// for them and for hijacked connections to finish.
+func (s *Server) drain(ctx context.Context) error {
+	s.stopDrain()
+	s.logger.Info("draining connections",
+		slog.Int64("in_flight", s.InFlight()),
+		slog.Int("hijacked", s.hijackedCount()),
+	)
+
+	err := errors.Join(s.server.Shutdown(ctx), s.shutdownHTTP3(ctx))
+	if err == nil {
+		err = s.waitDrained(ctx)
+	}
+	if ctx.Err() != nil {
+		s.forceClose()
+	}
+	return err
+}
+
+// waitDrained waits until no request is in flight and every hijacked
+// connection is closed.
+func (s *Server) waitDrained(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for s.InFlight() > 0 || s.hijackedCount() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// forceClose closes the connections left after the shutdown deadline.
+func (s *Server) forceClose() {
+	s.logger.Warn("shutdown timed out, closing remaining connections",
+		slog.Int64("in_flight", s.InFlight()),
+		slog.Int("hijacked", s.hijackedCount()),
+	)
+
+	_ = s.server.Close()
+
+	s.mu.Lock()
+	conns := make([]*hijackedConn, 0, len(s.hijacked))
+	for c := range s.hijacked {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		_ = c.Close()
+	}
+}
+
+func (s *Server) hijackedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.hijacked)
+}
+
+// drainWriter tracks connections hijacked by handlers so Shutdown can wait
+// for them and close them on timeout.
+type drainWriter struct {
+	http.ResponseWriter
+	server *Server
+}
+
+// Flush implements http.Flusher so streaming handlers keep working.
+func (w *drainWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker. The returned connection is tracked until
+// it is closed.
+func (w *drainWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hc := &hijackedConn{Conn: conn, server: w.server}
+	w.server.mu.Lock()
+	w.server.hijacked[hc] = struct{}{}
+	w.server.mu.Unlock()
+
+	return hc, brw, nil
+}
+
+// ReadFrom implements io.ReaderFrom so io.Copy into the response keeps using
+// the sendfile path of the underlying writer.
+func (w *drainWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *drainWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// hijackedConn stops being tracked once closed.
+type hijackedConn struct {
+	net.Conn
+	server *Server
+	once   sync.Once
+}
+
+func (c *hijackedConn) Close() error {
+	c.once.Do(func() {
+		c.server.mu.Lock()
+		delete(c.server.hijacked, c)
+		c.server.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
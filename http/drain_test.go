@@ -0,0 +1,243 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startTestServer starts srv and returns its base URL.
+func startTestServer(t *testing.T, srv *Server) string {
+	t.Helper()
+	go func() { _ = srv.Start() }()
+	select {
+	case <-srv.Ready():
+	case <-time.After(2 * time.Second):
+		t.Fatal("server not ready")
+	}
+	return "http://" + srv.Address()
+}
+
+func TestServerShutdown_NotifiesStreams(t *testing.T) {
+	var buf syncBuffer
+	started := make(chan struct{})
+	srv := NewServerWithConfig("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		close(started)
+		<-Draining(r.Context())
+		_, _ = w.Write([]byte("bye"))
+	}), testConfig("127.0.0.1:0"), slog.New(slog.NewTextHandler(&buf, nil)))
+	url := startTestServer(t, srv)
+
+	resp, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	<-started
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "bye" {
+		t.Errorf("expected stream to finish cleanly, got %q (%v)", body, err)
+	}
+	if !strings.Contains(buf.String(), `msg="draining connections" server=test in_flight=1`) {
+		t.Errorf("expected in-flight count to be logged, got %q", buf.String())
+	}
+}
+
+func TestServerShutdown_ClosesHijackedAfterTimeout(t *testing.T) {
+	hijacked := make(chan net.Conn, 1)
+	srv := NewServerWithConfig("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		_ = brw.Flush()
+		// Ignore Draining and keep the connection open
+		hijacked <- conn
+	}), testConfig("127.0.0.1:0"), testLogger())
+	url := startTestServer(t, srv)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	<-hijacked
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected shutdown to wait for the hijacked connection, got %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Errorf("expected the hijacked connection to be closed, got %v", err)
+	}
+	if n := srv.hijackedCount(); n != 0 {
+		t.Errorf("expected no tracked connections, got %d", n)
+	}
+}
+
+func TestServerShutdown_Delay(t *testing.T) {
+	cfg := testConfig("127.0.0.1:0")
+	cfg.HealthEndpoints = true
+	cfg.ShutdownDelay = 200 * time.Millisecond
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), cfg, testLogger())
+	url := startTestServer(t, srv)
+
+	done := runAsync(srv.Shutdown, context.Background())
+	for !srv.Health().ShuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+
+	resp, err := http.Get(url + "/readyz")
+	if err != nil {
+		t.Fatalf("expected the server to keep serving during the delay: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness to fail during the delay, got %d", resp.StatusCode)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if _, err := http.Get(url + "/readyz"); err == nil {
+		t.Error("expected connections to be refused after shutdown")
+	}
+}
+
+func TestDrainContext(t *testing.T) {
+	started := make(chan struct{})
+	srv := NewServerWithConfig("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := DrainContext(r.Context())
+		defer cancel()
+
+		w.(http.Flusher).Flush()
+		close(started)
+		<-ctx.Done()
+		_, _ = w.Write([]byte("bye"))
+	}), testConfig("127.0.0.1:0"), testLogger())
+	url := startTestServer(t, srv)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	<-started
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "bye" {
+		t.Errorf("expected the drain context to end the response, got %q (%v)", body, err)
+	}
+
+	// Outside a server, the context only follows its parent
+	ctx, cancel := DrainContext(context.Background())
+	if ctx.Err() != nil {
+		t.Error("expected the context to be live outside a server")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("expected cancel to end the context")
+	}
+}
+
+func TestDrainWriter_ReaderFrom(t *testing.T) {
+	var _ io.ReaderFrom = &drainWriter{}
+
+	srv := NewServerWithConfig("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(io.ReaderFrom); !ok {
+			t.Error("expected the response writer to implement io.ReaderFrom")
+		}
+		_, _ = io.Copy(w, strings.NewReader("copied"))
+	}), testConfig("127.0.0.1:0"), testLogger())
+	url := startTestServer(t, srv)
+	defer srv.Shutdown(context.Background())
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "copied" {
+		t.Errorf("expected copied body, got %q", body)
+	}
+}
+
+func TestServerManager_ShutdownDelayOnce(t *testing.T) {
+	const delay = 300 * time.Millisecond
+
+	sm := NewServerManager(testLogger())
+	servers := make([]*Server, 3)
+	for i := range servers {
+		cfg := testConfig("127.0.0.1:0")
+		cfg.ShutdownDelay = delay
+		servers[i] = NewServerWithConfig("test", http.NotFoundHandler(), cfg, testLogger())
+		sm.AddServer(servers[i])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(sm.Run, ctx)
+	for _, srv := range servers {
+		<-srv.Ready()
+	}
+
+	start := time.Now()
+	cancel()
+	for _, srv := range servers {
+		for !srv.Health().ShuttingDown() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < delay || elapsed >= 2*delay {
+		t.Errorf("expected the shutdown delay to be waited once, took %v", elapsed)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent log writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,143 @@
+package goxhttptest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Client sends requests to a test server. Requests that fail to complete
+// fail the test.
+type Client struct {
+	// BaseURL is prepended to request paths.
+	BaseURL string
+	// HTTP is the underlying client.
+	HTTP *http.Client
+
+	t testing.TB
+}
+
+func newClient(t testing.TB, baseURL string, insecureTLS bool) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecureTLS {
+		// Test servers use self-signed certificates
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTP:    &http.Client{Transport: transport, Timeout: waitTimeout},
+		t:       t,
+	}
+}
+
+// Response is a response with its body already read.
+type Response struct {
+	*http.Response
+	Body []byte
+
+	t testing.TB
+}
+
+// ExpectStatus fails the test if the response status is not code.
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Fatalf("expected status %d, got %d: %s", code, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// JSON decodes the body of r into a T, failing the test if it can't.
+func JSON[T any](r *Response) T {
+	r.t.Helper()
+	var v T
+	if err := json.Unmarshal(r.Body, &v); err != nil {
+		r.t.Fatalf("failed to decode response body %q: %v", r.Body, err)
+	}
+	return v
+}
+
+// Get sends a GET request for path.
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	return c.Do(c.NewRequest(http.MethodGet, path, nil))
+}
+
+// Post sends a POST request for path with body of contentType.
+func (c *Client) Post(path, contentType string, body []byte) *Response {
+	c.t.Helper()
+	req := c.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// PostJSON sends a POST request for path with v encoded as JSON.
+func (c *Client) PostJSON(path string, v any) *Response {
+	c.t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatalf("failed to encode request body: %v", err)
+	}
+	return c.Post(path, "application/json", body)
+}
+
+// NewRequest returns a request for path, relative to BaseURL.
+func (c *Client) NewRequest(method, path string, body []byte) *http.Request {
+	c.t.Helper()
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), method, c.BaseURL+path, r)
+	if err != nil {
+		c.t.Fatalf("failed to create request: %v", err)
+	}
+	return req
+}
+
+// Do sends req and reads the whole response body.
+func (c *Client) Do(req *http.Request) *Response {
+	c.t.Helper()
+	resp, err := c.do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s failed: %v", req.Method, req.URL.Path, err)
+	}
+	return resp
+}
+
+// Async sends req in the background. The returned channel receives the
+// response, or nil and an error reported to the test if the request fails.
+// It lets a test keep a request in flight while it shuts the server down.
+func (c *Client) Async(req *http.Request) <-chan *Response {
+	ch := make(chan *Response, 1)
+	go func() {
+		resp, err := c.do(req)
+		if err != nil {
+			// Fatalf must not be called outside the test goroutine
+			c.t.Errorf("%s %s failed: %v", req.Method, req.URL.Path, err)
+		}
+		ch <- resp
+	}()
+	return ch
+}
+
+func (c *Client) do(req *http.Request) (*Response, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Response: resp, Body: body, t: c.t}, nil
+}
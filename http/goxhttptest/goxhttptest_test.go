@@ -0,0 +1,84 @@
+package goxhttptest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	goxhttp "github.com/guilhermebr/gox/http"
+)
+
+func TestServer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /echo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"name":"gox"}`))
+	})
+
+	srv := Start(t, mux)
+
+	resp := srv.Client().PostJSON("/echo", map[string]string{"name": "gox"}).ExpectStatus(http.StatusCreated)
+	body := JSON[map[string]string](resp)
+	if body["name"] != "gox" {
+		t.Errorf("expected decoded body, got %v", body)
+	}
+
+	entry := srv.WaitAccessLog(http.MethodPost, "/echo")
+	if entry.Status != http.StatusCreated {
+		t.Errorf("expected access log status 201, got %d", entry.Status)
+	}
+	if entry.Route != "POST /echo" {
+		t.Errorf("expected access log route, got %q", entry.Route)
+	}
+	if entry.RequestID == "" || entry.RequestID != resp.Header.Get("X-Request-ID") {
+		t.Errorf("expected access log request ID to match the response, got %q", entry.RequestID)
+	}
+}
+
+func TestServer_GracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := Start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = w.Write([]byte("done"))
+	}))
+
+	client := srv.Client()
+	inFlight := client.Async(client.NewRequest(http.MethodGet, "/slow", nil))
+	<-started
+
+	stopped := srv.BeginShutdown()
+	select {
+	case err := <-stopped:
+		t.Fatalf("expected shutdown to wait for the in-flight request, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if resp := <-inFlight; resp != nil {
+		resp.ExpectStatus(http.StatusOK)
+		if string(resp.Body) != "done" {
+			t.Errorf("expected complete body, got %q", resp.Body)
+		}
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("expected clean shutdown, got %v", err)
+	}
+}
+
+func TestNewTracerProvider(t *testing.T) {
+	tp, spans := NewTracerProvider()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {})
+
+	srv := Start(t, goxhttp.Tracing("test", tp)(mux))
+	srv.Client().Get("/ping").ExpectStatus(http.StatusOK)
+
+	got := spans.GetSpans()
+	if len(got) != 1 || got[0].Name != "GET /ping" {
+		t.Fatalf("expected one span named GET /ping, got %v", got)
+	}
+}
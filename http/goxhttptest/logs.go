@@ -0,0 +1,194 @@
+package goxhttptest
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// accessLogMessage is the message of goxhttp.AccessLog entries.
+const accessLogMessage = "http request"
+
+// Record is a captured log record. Attrs are keyed by their name, prefixed
+// with their groups ("applied.write_timeout.new").
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   map[string]any
+}
+
+// AccessEntry is a captured goxhttp.AccessLog entry.
+type AccessEntry struct {
+	Method    string
+	Route     string
+	Path      string
+	Status    int
+	Bytes     int64
+	Latency   time.Duration
+	RequestID string
+}
+
+// LogRecorder captures slog records in memory.
+type LogRecorder struct {
+	mu      sync.Mutex
+	records []Record
+	added   chan struct{}
+}
+
+// NewLogRecorder returns an empty recorder.
+func NewLogRecorder() *LogRecorder {
+	return &LogRecorder{added: make(chan struct{})}
+}
+
+// Logger returns a logger that records into l at every level.
+func (l *LogRecorder) Logger() *slog.Logger {
+	return slog.New(&recordHandler{recorder: l})
+}
+
+// Records returns the records captured so far.
+func (l *LogRecorder) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Record(nil), l.records...)
+}
+
+// AccessLogs returns the access log entries captured so far.
+func (l *LogRecorder) AccessLogs() []AccessEntry {
+	var entries []AccessEntry
+	for _, r := range l.Records() {
+		if r.Message == accessLogMessage {
+			entries = append(entries, accessEntry(r))
+		}
+	}
+	return entries
+}
+
+// WaitFor returns the first record matching match, waiting for it to be
+// logged if needed. The test fails if none is logged within 5s.
+func (l *LogRecorder) WaitFor(t testing.TB, match func(Record) bool) Record {
+	t.Helper()
+
+	timeout := time.After(waitTimeout)
+	for {
+		l.mu.Lock()
+		for _, r := range l.records {
+			if match(r) {
+				l.mu.Unlock()
+				return r
+			}
+		}
+		added := l.added
+		l.mu.Unlock()
+
+		select {
+		case <-added:
+		case <-timeout:
+			t.Fatalf("no matching log record after %v", waitTimeout)
+			return Record{}
+		}
+	}
+}
+
+func (l *LogRecorder) add(r Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, r)
+	close(l.added)
+	l.added = make(chan struct{})
+}
+
+func accessEntry(r Record) AccessEntry {
+	e := AccessEntry{}
+	e.Method, _ = r.Attrs["method"].(string)
+	e.Route, _ = r.Attrs["route"].(string)
+	e.Path, _ = r.Attrs["path"].(string)
+	if status, ok := r.Attrs["status"].(int64); ok {
+		e.Status = int(status)
+	}
+	e.Bytes, _ = r.Attrs["bytes"].(int64)
+	e.Latency, _ = r.Attrs["latency"].(time.Duration)
+	e.RequestID, _ = r.Attrs["request_id"].(string)
+	return e
+}
+
+// recordHandler is the slog.Handler behind LogRecorder.Logger.
+type recordHandler struct {
+	recorder *LogRecorder
+	attrs    []slog.Attr
+	groups   []string
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	rec := Record{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   make(map[string]any),
+	}
+	for _, a := range h.attrs {
+		addAttr(rec.Attrs, "", a)
+	}
+
+	prefix := ""
+	for _, g := range h.groups {
+		prefix += g + "."
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(rec.Attrs, prefix, a)
+		return true
+	})
+
+	h.recorder.add(rec)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := ""
+	for _, g := range h.groups {
+		prefix += g + "."
+	}
+
+	// Store grouped attrs under their full name so Handle can add them as is
+	next := &recordHandler{recorder: h.recorder, groups: h.groups}
+	next.attrs = append(next.attrs, h.attrs...)
+	for _, a := range attrs {
+		next.attrs = append(next.attrs, slog.Attr{Key: prefix + a.Key, Value: a.Value})
+	}
+	return next
+}
+
+func (h *recordHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &recordHandler{
+		recorder: h.recorder,
+		attrs:    h.attrs,
+		groups:   append(append([]string(nil), h.groups...), name),
+	}
+}
+
+// addAttr adds a to attrs, flattening groups into dotted keys.
+func addAttr(attrs map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(attrs, groupPrefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	attrs[prefix+a.Key] = v.Any()
+}
@@ -0,0 +1,160 @@
+// Package goxhttptest runs a real goxhttp.Server in-process for tests, with
+// its logs captured for assertions.
+package goxhttptest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	goxhttp "github.com/guilhermebr/gox/http"
+)
+
+// waitTimeout bounds how long helpers wait for the server or its logs.
+const waitTimeout = 5 * time.Second
+
+// Server is a goxhttp.Server listening on an ephemeral local port.
+type Server struct {
+	*goxhttp.Server
+
+	// URL is the base URL of the server, without a trailing slash.
+	URL string
+	// Logs holds everything the server logged.
+	Logs *LogRecorder
+
+	t        testing.TB
+	cancel   context.CancelFunc
+	done     chan error
+	waitOnce sync.Once
+	result   error
+}
+
+// DefaultConfig returns the config used by Start: a 127.0.0.1:0 listener,
+// the standard middleware and short timeouts. Metrics and tracing are
+// disabled so tests don't share global state.
+func DefaultConfig() goxhttp.Config {
+	return goxhttp.Config{
+		Address:           "127.0.0.1:0",
+		ReadHeaderTimeout: waitTimeout,
+		ReadTimeout:       waitTimeout,
+		WriteTimeout:      waitTimeout,
+		IdleTimeout:       waitTimeout,
+		ShutdownTimeout:   waitTimeout,
+		HealthEndpoints:   true,
+		RequestID:         true,
+		AccessLog:         true,
+		RecoverPanics:     true,
+	}
+}
+
+// Start serves handler with DefaultConfig and returns once the server
+// accepts connections. The server is shut down when the test ends.
+func Start(t testing.TB, handler http.Handler) *Server {
+	t.Helper()
+	return StartWithConfig(t, handler, DefaultConfig())
+}
+
+// StartWithConfig serves handler with cfg and returns once the server
+// accepts connections. The server is shut down when the test ends.
+func StartWithConfig(t testing.TB, handler http.Handler, cfg goxhttp.Config) *Server {
+	t.Helper()
+
+	logs := NewLogRecorder()
+	srv := goxhttp.NewServerWithConfig("test", handler, cfg, logs.Logger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		Server: srv,
+		Logs:   logs,
+		t:      t,
+		cancel: cancel,
+		done:   make(chan error, 1),
+	}
+
+	go func() {
+		s.done <- srv.Run(ctx)
+	}()
+
+	select {
+	case <-srv.Ready():
+	case err := <-s.done:
+		cancel()
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(waitTimeout):
+		cancel()
+		t.Fatalf("server not ready after %v", waitTimeout)
+	}
+
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	s.URL = scheme + "://" + srv.Address()
+
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("server shutdown failed: %v", err)
+		}
+	})
+
+	return s
+}
+
+// BeginShutdown starts a graceful shutdown and returns as soon as the server
+// reports not ready. The listeners may still accept connections at that
+// point: they are closed after ShutdownDelay, when the server stops taking
+// new requests. Requests already in flight keep running; the returned
+// channel receives the result of the shutdown once they have completed.
+func (s *Server) BeginShutdown() <-chan error {
+	s.t.Helper()
+
+	s.cancel()
+
+	deadline := time.Now().Add(waitTimeout)
+	for !s.Health().ShuttingDown() {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("server did not begin shutting down after %v", waitTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- s.wait()
+	}()
+	return result
+}
+
+// Close shuts the server down gracefully and waits for it to stop. It is
+// safe to call more than once.
+func (s *Server) Close() error {
+	s.cancel()
+	return s.wait()
+}
+
+func (s *Server) wait() error {
+	s.waitOnce.Do(func() {
+		s.result = <-s.done
+	})
+	return s.result
+}
+
+// Client returns a client for the server. Paths passed to it are relative
+// to URL.
+func (s *Server) Client() *Client {
+	return newClient(s.t, s.URL, s.Config().TLSEnabled())
+}
+
+// WaitAccessLog waits for the access log entry of a request with method and
+// path and returns it. Access logs are written after the response, so a
+// client may see the response before the entry exists.
+func (s *Server) WaitAccessLog(method, path string) AccessEntry {
+	s.t.Helper()
+
+	record := s.Logs.WaitFor(s.t, func(r Record) bool {
+		return r.Message == accessLogMessage && r.Attrs["method"] == method && r.Attrs["path"] == path
+	})
+	return accessEntry(record)
+}
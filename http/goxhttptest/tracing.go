@@ -0,0 +1,14 @@
+package goxhttptest
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// NewTracerProvider returns a tracer provider that exports spans
+// synchronously to an in-memory exporter, for asserting on spans in tests.
+// Pass it to goxhttp.Tracing or goxhttp.TracingTransport.
+func NewTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
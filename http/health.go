@@ -0,0 +1,228 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health endpoint paths served when Config.HealthEndpoints is enabled.
+const (
+	LivenessPath  = "/livez"
+	ReadinessPath = "/readyz"
+	HealthPath    = "/healthz"
+)
+
+// DefaultHealthCheckTimeout is used for checks registered without a timeout.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheckFunc reports the health of a component. It must honour ctx
+// cancellation. postgres.DatabasePool.Ping satisfies this signature.
+type HealthCheckFunc func(ctx context.Context) error
+
+type healthCheck struct {
+	name    string
+	timeout time.Duration
+	check   HealthCheckFunc
+}
+
+// CheckResult is the outcome of a single health check.
+type CheckResult struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// HealthReport is the detailed health response served on HealthPath.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Health status values
+const (
+	HealthStatusOK           = "ok"
+	HealthStatusFail         = "fail"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// Health is a registry of named health checks backing the liveness,
+// readiness and health endpoints.
+type Health struct {
+	mu           sync.RWMutex
+	checks       []healthCheck
+	shuttingDown atomic.Bool
+}
+
+// NewHealth creates an empty health registry.
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// Register adds a named check. A zero timeout uses DefaultHealthCheckTimeout.
+// Registering a name twice replaces the previous check.
+func (h *Health) Register(name string, timeout time.Duration, check HealthCheckFunc) {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hc := healthCheck{name: name, timeout: timeout, check: check}
+	for i := range h.checks {
+		if h.checks[i].name == name {
+			h.checks[i] = hc
+			return
+		}
+	}
+	h.checks = append(h.checks, hc)
+}
+
+// SetShuttingDown marks the service as shutting down so readiness fails and
+// load balancers stop routing new traffic.
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether SetShuttingDown has been called.
+func (h *Health) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Check runs all registered checks concurrently and returns the report.
+func (h *Health) Check(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	checks := make([]healthCheck, len(h.checks))
+	copy(checks, h.checks)
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, hc := range checks {
+		wg.Add(1)
+		go func(i int, hc healthCheck) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, hc)
+		}(i, hc)
+	}
+	wg.Wait()
+
+	report := HealthReport{
+		Status: HealthStatusOK,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+	for i, hc := range checks {
+		report.Checks[hc.name] = results[i]
+		if results[i].Status != HealthStatusOK {
+			report.Status = HealthStatusFail
+		}
+	}
+	if h.ShuttingDown() {
+		report.Status = HealthStatusShuttingDown
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, hc healthCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- hc.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Status:     HealthStatusOK,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler reports whether the process is alive. It does not run any
+// checks.
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthStatus(w, http.StatusOK, HealthStatusOK)
+	})
+}
+
+// ReadinessHandler reports whether the service can accept traffic. It fails
+// once shutdown has begun or when any check fails.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.ShuttingDown() {
+			writeHealthStatus(w, http.StatusServiceUnavailable, HealthStatusShuttingDown)
+			return
+		}
+
+		report := h.Check(r.Context())
+		if report.Status != HealthStatusOK {
+			writeHealthStatus(w, http.StatusServiceUnavailable, report.Status)
+			return
+		}
+		writeHealthStatus(w, http.StatusOK, HealthStatusOK)
+	})
+}
+
+// HealthHandler serves the detailed JSON HealthReport.
+func (h *Health) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+
+		status := http.StatusOK
+		if report.Status != HealthStatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Handler routes the health endpoint paths to the registry and everything
+// else to next.
+func (h *Health) Handler(next http.Handler) http.Handler {
+	liveness := h.LivenessHandler()
+	readiness := h.ReadinessHandler()
+	health := h.HealthHandler()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case LivenessPath:
+			liveness.ServeHTTP(w, r)
+		case ReadinessPath:
+			readiness.ServeHTTP(w, r)
+		case HealthPath:
+			health.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func writeHealthStatus(w http.ResponseWriter, code int, status string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = w.Write([]byte(status))
+}
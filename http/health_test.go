@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandler_Endpoints(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		check        HealthCheckFunc
+		shuttingDown bool
+		wantStatus   int
+	}{
+		{
+			name:       "liveness",
+			path:       LivenessPath,
+			check:      func(context.Context) error { return errors.New("db down") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "readiness ok",
+			path:       ReadinessPath,
+			check:      func(context.Context) error { return nil },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "readiness failing check",
+			path:       ReadinessPath,
+			check:      func(context.Context) error { return errors.New("db down") },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "readiness shutting down",
+			path:         ReadinessPath,
+			check:        func(context.Context) error { return nil },
+			shuttingDown: true,
+			wantStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:       "health failing check",
+			path:       HealthPath,
+			check:      func(context.Context) error { return errors.New("db down") },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "passthrough",
+			path:       "/other",
+			check:      func(context.Context) error { return errors.New("db down") },
+			wantStatus: http.StatusTeapot,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealth()
+			h.Register("db", time.Second, tt.check)
+			if tt.shuttingDown {
+				h.SetShuttingDown()
+			}
+
+			rec := httptest.NewRecorder()
+			h.Handler(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHealthCheck_Report(t *testing.T) {
+	h := NewHealth()
+	h.Register("db", time.Second, func(context.Context) error { return nil })
+	h.Register("cache", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	rec := httptest.NewRecorder()
+	h.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+
+	var report HealthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+
+	if report.Status != HealthStatusFail {
+		t.Errorf("expected status %s, got %s", HealthStatusFail, report.Status)
+	}
+	if report.Checks["db"].Status != HealthStatusOK {
+		t.Errorf("expected db check ok, got %+v", report.Checks["db"])
+	}
+	if report.Checks["cache"].Status != HealthStatusFail || report.Checks["cache"].Error == "" {
+		t.Errorf("expected cache check to time out, got %+v", report.Checks["cache"])
+	}
+}
+
+func TestServerShutdown_FailsReadiness(t *testing.T) {
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), Config{HealthEndpoints: true}, testLogger())
+
+	if srv.Health().ShuttingDown() {
+		t.Fatal("expected server not to be shutting down")
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness to fail after shutdown, got %d", rec.Code)
+	}
+}
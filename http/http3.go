@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// configureProtocols applies the h2c and HTTP/2 settings from the config.
+func (s *Server) configureProtocols() {
+	if s.config.H2C {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		s.server.Protocols = &protocols
+	}
+
+	if s.config.HTTP2MaxConcurrentStreams > 0 {
+		s.server.HTTP2 = &http.HTTP2Config{
+			MaxConcurrentStreams: s.config.HTTP2MaxConcurrentStreams,
+		}
+	}
+}
+
+// maxHeaderListSize rejects HTTP/2 requests whose header list is larger than
+// limit with 431 Request Header Fields Too Large. Neither net/http nor
+// x/net/http2 has a setting for it that leaves HTTP/1 alone: both derive
+// SETTINGS_MAX_HEADER_LIST_SIZE from http.Server.MaxHeaderBytes, which also
+// caps HTTP/1 headers, so the limit is enforced here instead.
+func maxHeaderListSize(limit int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor == 2 && headerListSize(r) > limit {
+				http.Error(w, http.StatusText(http.StatusRequestHeaderFieldsTooLarge), http.StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// headerListSize returns the size of the header list of r as defined for
+// SETTINGS_MAX_HEADER_LIST_SIZE (RFC 9113 6.5.2): the length of each name
+// and value plus 32 per field, pseudo-header fields included.
+func headerListSize(r *http.Request) int {
+	size := len(":method") + len(r.Method) + len(":path") + len(r.URL.RequestURI()) +
+		len(":scheme") + len("https") + len(":authority") + len(r.Host) + 4*32
+	for name, values := range r.Header {
+		for _, value := range values {
+			size += len(name) + len(value) + 32
+		}
+	}
+	return size
+}
+
+// startHTTP3 serves HTTP/3 over QUIC on the UDP counterpart of ln, on
+// HTTP3Address when set, or on the socket inherited on upgrade, and
+// advertises it to TCP clients with Alt-Svc.
+func (s *Server) startHTTP3(ln net.Listener, tlsCfg *tls.Config) error {
+	pc, ok := inheritedPacketConn(s.server.Addr)
+	if !ok {
+		addr := s.config.HTTP3Address
+		if addr == "" {
+			if ln.Addr().Network() != "tcp" {
+				return fmt.Errorf("failed to start http3: http3 with a %s listener requires HTTP3Address", ln.Addr().Network())
+			}
+			addr = ln.Addr().String()
+		}
+
+		var err error
+		pc, err = net.ListenPacket("udp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for http3: %w", err)
+		}
+	}
+
+	h3 := &http3.Server{
+		Handler:        s.server.Handler,
+		TLSConfig:      http3.ConfigureTLSConfig(tlsCfg),
+		MaxHeaderBytes: s.server.MaxHeaderBytes,
+		IdleTimeout:    s.server.IdleTimeout,
+	}
+
+	s.mu.Lock()
+	s.http3 = h3
+	s.http3Conn = pc
+	s.mu.Unlock()
+
+	s.server.Handler = altSvc(pc.LocalAddr().(*net.UDPAddr).Port, s.server.Handler)
+
+	s.logger.Info("starting http3 server",
+		slog.String("address", pc.LocalAddr().String()),
+	)
+
+	go func() {
+		if err := h3.Serve(pc); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			s.logger.Error("http3 server failed",
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
+
+	return nil
+}
+
+// shutdownHTTP3 gracefully stops the HTTP/3 server, if any.
+func (s *Server) shutdownHTTP3(ctx context.Context) error {
+	s.mu.Lock()
+	h3, pc := s.http3, s.http3Conn
+	s.mu.Unlock()
+
+	if h3 == nil {
+		return nil
+	}
+
+	err := h3.Shutdown(ctx)
+	pc.Close()
+	return err
+}
+
+// altSvc advertises the HTTP/3 endpoint on port to clients connected over
+// TCP.
+func altSvc(port int, next http.Handler) http.Handler {
+	value := fmt.Sprintf(`%s=":%d"; ma=2592000`, http3.NextProtoH3, port)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			w.Header().Set("Alt-Svc", value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
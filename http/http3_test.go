@@ -0,0 +1,179 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func protoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+}
+
+func TestServer_H2C(t *testing.T) {
+	cfg := testConfig("127.0.0.1:0")
+	cfg.H2C = true
+	cfg.HTTP2MaxConcurrentStreams = 10
+
+	srv := NewServerWithConfig("test", protoHandler(), cfg, testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-srv.Ready()
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+
+	resp, err := client.Get("http://" + srv.Address())
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2 response, got %s", resp.Proto)
+	}
+}
+
+func TestServer_HTTP2MaxHeaderListSize(t *testing.T) {
+	cfg := testConfig("127.0.0.1:0")
+	cfg.H2C = true
+	cfg.HTTP2MaxHeaderListSize = 1024
+
+	srv := NewServerWithConfig("test", protoHandler(), cfg, testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-srv.Ready()
+
+	var h2c http.Protocols
+	h2c.SetUnencryptedHTTP2(true)
+
+	tests := []struct {
+		name      string
+		transport *http.Transport
+		header    string
+		want      int
+	}{
+		{name: "http2 small", transport: &http.Transport{Protocols: &h2c}, header: "x", want: http.StatusOK},
+		{name: "http2 large", transport: &http.Transport{Protocols: &h2c}, header: strings.Repeat("x", 2048), want: http.StatusRequestHeaderFieldsTooLarge},
+		{name: "http1 large", transport: &http.Transport{}, header: strings.Repeat("x", 2048), want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "http://"+srv.Address(), nil)
+			req.Header.Set("X-Large", tt.header)
+
+			resp, err := (&http.Client{Transport: tt.transport}).Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServer_HTTP3(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "server")
+
+	cfg := testConfig("127.0.0.1:0")
+	cfg.TLSCertFile = certFile
+	cfg.TLSKeyFile = keyFile
+	cfg.HTTP3 = true
+
+	srv := NewServerWithConfig("test", protoHandler(), cfg, testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-srv.Ready()
+
+	rootPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("failed to read cert: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(rootPEM)
+	tlsCfg := &tls.Config{RootCAs: roots}
+
+	tcpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+	resp, err := tcpClient.Get("https://" + srv.Address())
+	if err != nil {
+		t.Fatalf("tcp request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	port := srv.Address()[strings.LastIndex(srv.Address(), ":")+1:]
+	if want := `h3=":` + port + `"; ma=2592000`; resp.Header.Get("Alt-Svc") != want {
+		t.Errorf("expected Alt-Svc %q, got %q", want, resp.Header.Get("Alt-Svc"))
+	}
+
+	h3Transport := &http3.Transport{TLSClientConfig: tlsCfg}
+	defer h3Transport.Close()
+
+	resp, err = (&http.Client{Transport: h3Transport}).Get("https://" + srv.Address())
+	if err != nil {
+		t.Fatalf("http3 request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.ProtoMajor != 3 {
+		t.Errorf("expected HTTP/3 response, got %s", resp.Proto)
+	}
+	if resp.Header.Get("Alt-Svc") != "" {
+		t.Error("expected no Alt-Svc header over HTTP/3")
+	}
+}
+
+func TestServer_HTTP3RequiresTLS(t *testing.T) {
+	cfg := testConfig("127.0.0.1:0")
+	cfg.HTTP3 = true
+
+	srv := NewServerWithConfig("test", protoHandler(), cfg, testLogger())
+	if err := srv.Run(context.Background()); err == nil {
+		t.Fatal("expected error when http3 is enabled without tls")
+	}
+}
+
+func TestServer_HTTP3UnixListenerRequiresAddress(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "server")
+
+	cfg := testConfig(UnixAddressPrefix + filepath.Join(dir, "app.sock"))
+	cfg.TLSCertFile = certFile
+	cfg.TLSKeyFile = keyFile
+	cfg.HTTP3 = true
+
+	srv := NewServerWithConfig("test", protoHandler(), cfg, testLogger())
+	err := srv.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "requires HTTP3Address") {
+		t.Fatalf("expected error asking for HTTP3Address, got: %v", err)
+	}
+}
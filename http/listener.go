@@ -0,0 +1,96 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Address prefixes understood by Listen
+const (
+	UnixAddressPrefix    = "unix:"
+	SystemdAddressPrefix = "systemd:"
+)
+
+// listenFDsStart is the first file descriptor passed to a process by systemd
+// socket activation or by an upgrade.
+const listenFDsStart = 3
+
+// Listen creates a listener for addr, which is one of:
+//   - a TCP address such as "0.0.0.0:3000" or "127.0.0.1:0"
+//   - "unix:/run/app.sock" for a Unix domain socket
+//   - "systemd:" or "systemd:<name>" for a socket passed by systemd socket
+//     activation, selected by its FileDescriptorName when a name is given
+func Listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, SystemdAddressPrefix):
+		return systemdListener(strings.TrimPrefix(addr, SystemdAddressPrefix))
+	case strings.HasPrefix(addr, UnixAddressPrefix):
+		return listenUnix(strings.TrimPrefix(addr, UnixAddressPrefix))
+	}
+
+	if addr == "" {
+		addr = ":http"
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenUnix listens on a Unix domain socket, removing a stale socket file
+// left behind by a previous process. A socket file is only considered stale
+// when connecting to it is refused; one with a live listener is left alone.
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("unix socket path is empty")
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		switch {
+		case err == nil:
+			conn.Close()
+			return nil, fmt.Errorf("unix socket [%s] is in use by another process", path)
+		case errors.Is(err, syscall.ECONNREFUSED):
+			if err := os.Remove(path); err != nil {
+				return nil, fmt.Errorf("removing stale unix socket [%s]: %w", path, err)
+			}
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+// listenerAddress returns the address of ln in the format accepted by Listen.
+func listenerAddress(ln net.Listener) string {
+	if ln.Addr().Network() == "unix" {
+		return UnixAddressPrefix + ln.Addr().String()
+	}
+	return ln.Addr().String()
+}
+
+// namedListener is a socket-activated listener and its systemd name.
+type namedListener struct {
+	name     string
+	listener net.Listener
+	claimed  bool
+}
+
+// claimListener returns the first unclaimed listener matching name, or the
+// first unclaimed listener when name is empty.
+func claimListener(listeners []*namedListener, name string) (net.Listener, error) {
+	for _, l := range listeners {
+		if l.claimed || (name != "" && l.name != name) {
+			continue
+		}
+		l.claimed = true
+		return l.listener, nil
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("no unclaimed systemd socket-activated listener")
+	}
+	return nil, fmt.Errorf("no unclaimed systemd socket-activated listener named [%s]", name)
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServerStartWithListener_Address(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), testConfig(":0"), testLogger())
+	if srv.Address() != ":0" {
+		t.Errorf("expected configured address before start, got %s", srv.Address())
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.StartWithListener(ln)
+	}()
+	<-srv.Ready()
+
+	if srv.Address() != ln.Addr().String() {
+		t.Errorf("expected bound address %s, got %s", ln.Addr(), srv.Address())
+	}
+
+	resp, err := http.Get("http://" + srv.Address())
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+}
+
+func TestServerStart_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	// A stale socket left behind by a previous process must not prevent binding
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), testConfig(UnixAddressPrefix+path), testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-srv.Ready()
+
+	if srv.Address() != UnixAddressPrefix+path {
+		t.Errorf("expected address %s, got %s", UnixAddressPrefix+path, srv.Address())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestListen_UnixSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	live, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer live.Close()
+
+	if _, err := Listen(UnixAddressPrefix + path); err == nil {
+		t.Fatal("expected error for a socket with a live listener")
+	}
+
+	// The live listener must keep its socket file
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("expected live socket to stay reachable: %v", err)
+	}
+	conn.Close()
+}
+
+func TestListen_SystemdWithoutActivation(t *testing.T) {
+	_, err := Listen(SystemdAddressPrefix)
+	if err == nil {
+		t.Fatal("expected error without socket activation")
+	}
+}
+
+func TestClaimListener(t *testing.T) {
+	newListener := func(name string) *namedListener {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		return &namedListener{name: name, listener: ln}
+	}
+
+	api := newListener("api")
+	admin := newListener("admin")
+	listeners := []*namedListener{api, admin}
+
+	got, err := claimListener(listeners, "admin")
+	if err != nil || got != admin.listener {
+		t.Fatalf("expected admin listener, got %v, %v", got, err)
+	}
+
+	if _, err := claimListener(listeners, "admin"); err == nil || !strings.Contains(err.Error(), "admin") {
+		t.Fatalf("expected error claiming admin twice, got %v", err)
+	}
+
+	got, err = claimListener(listeners, "")
+	if err != nil || got != api.listener {
+		t.Fatalf("expected first unclaimed listener, got %v, %v", got, err)
+	}
+
+	if _, err := claimListener(listeners, ""); err == nil {
+		t.Fatal("expected error when all listeners are claimed")
+	}
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsPath is the path the metrics server exposes Prometheus metrics on.
+const MetricsPath = "/metrics"
+
+// unmatchedRoute labels requests not matched by a ServeMux pattern, keeping
+// label cardinality bounded.
+const unmatchedRoute = "unmatched"
+
+// ServerMetrics tracks HTTP server request performance
+type ServerMetrics struct {
+	requestsTotal    *prometheus.CounterVec
+	requestsInFlight *prometheus.GaugeVec
+	requestDuration  *prometheus.HistogramVec
+	responseSize     *prometheus.HistogramVec
+	requestsRejected *prometheus.CounterVec
+}
+
+// NewServerMetrics creates HTTP server metrics registered with reg
+func NewServerMetrics(reg prometheus.Registerer) *ServerMetrics {
+	factory := promauto.With(reg)
+
+	return &ServerMetrics{
+		requestsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests handled",
+		}, []string{"server", "method", "route", "code"}),
+		requestsInFlight: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being handled",
+		}, []string{"server", "method"}),
+		requestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request handling duration",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1ms to ~32s
+		}, []string{"server", "method", "route", "code"}),
+		responseSize: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body size",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 10), // 64B to ~16MB
+		}, []string{"server", "method", "route", "code"}),
+		requestsRejected: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_rejected_total",
+			Help: "Total number of HTTP requests rejected by rate or concurrency limits",
+		}, []string{"server", "reason"}),
+	}
+}
+
+// defaultServerMetrics is shared by all servers in the process since metrics
+// can only be registered once with the default registry.
+var defaultServerMetrics = sync.OnceValue(func() *ServerMetrics {
+	return NewServerMetrics(prometheus.DefaultRegisterer)
+})
+
+// Middleware records request metrics labelled with the given server name.
+// The route label is the matched ServeMux pattern.
+func (m *ServerMetrics) Middleware(server string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := metricMethod(r.Method)
+			inFlight := m.requestsInFlight.WithLabelValues(server, method)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			rw := wrapResponseWriter(w)
+
+			next.ServeHTTP(rw, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = unmatchedRoute
+			}
+			code := strconv.Itoa(rw.Status())
+
+			m.requestsTotal.WithLabelValues(server, method, route, code).Inc()
+			m.requestDuration.WithLabelValues(server, method, route, code).Observe(time.Since(start).Seconds())
+			m.responseSize.WithLabelValues(server, method, route, code).Observe(float64(rw.BytesWritten()))
+		})
+	}
+}
+
+// RecordRejection counts a request rejected by a limiter.
+func (m *ServerMetrics) RecordRejection(server, reason string) {
+	m.requestsRejected.WithLabelValues(server, reason).Inc()
+}
+
+// metricMethod maps non-standard methods to OTHER so clients can't create
+// arbitrary label values.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// MetricsHandler returns the Prometheus handler for the default registry.
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
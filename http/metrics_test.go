@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestServerMetrics_Middleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewServerMetrics(reg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if m := testutil.ToFloat64(m.requestsInFlight.WithLabelValues("api", http.MethodGet)); m != 1 {
+			t.Errorf("expected 1 request in flight, got %v", m)
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	h := m.Middleware("api")(mux)
+	for _, path := range []string{"/orders/1", "/orders/2", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/orders/1", nil))
+
+	tests := []struct {
+		route, method, code string
+		want                float64
+	}{
+		{route: "GET /orders/{id}", method: "GET", code: "200", want: 2},
+		{route: unmatchedRoute, method: "GET", code: "404", want: 1},
+		{route: unmatchedRoute, method: "OTHER", code: "405", want: 1},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(m.requestsTotal.WithLabelValues("api", tt.method, tt.route, tt.code))
+		if got != tt.want {
+			t.Errorf("expected %v requests for %s %s %s, got %v", tt.want, tt.method, tt.route, tt.code, got)
+		}
+	}
+
+	if got := testutil.ToFloat64(m.requestsInFlight.WithLabelValues("api", http.MethodGet)); got != 0 {
+		t.Errorf("expected no requests in flight, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.requestDuration); got != 3 {
+		t.Errorf("expected 3 duration series, got %d", got)
+	}
+}
+
+func TestServerManager_AddMetricsServer(t *testing.T) {
+	sm := NewServerManager(testLogger())
+	sm.AddServer(NewServerWithConfig("api", http.NotFoundHandler(), testConfig("127.0.0.1:0"), testLogger()))
+	metrics := sm.AddMetricsServer("metrics", testConfig("127.0.0.1:0"))
+
+	if sm.servers[0] != metrics {
+		t.Fatal("expected metrics server to start first")
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "go_goroutines") {
+		t.Error("expected default registry metrics in response")
+	}
+}
@@ -0,0 +1,222 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 9457 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. It implements error so
+// handlers can return it directly.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions are additional members serialized next to the standard
+	// ones.
+	Extensions map[string]any `json:"-"`
+
+	err error
+}
+
+// NewProblem returns a problem with status, its status text as title and
+// detail.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Wrap attaches the underlying cause to p, for errors.Is and logging.
+func (p *Problem) Wrap(err error) *Problem {
+	p.err = err
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) Unwrap() error { return p.err }
+
+// MarshalJSON flattens Extensions into the problem object. Standard members
+// take precedence over extensions with the same name.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+
+	type problem Problem
+	b, err := json.Marshal((*problem)(p))
+	if err != nil {
+		return nil, err
+	}
+	var standard map[string]any
+	if err := json.Unmarshal(b, &standard); err != nil {
+		return nil, err
+	}
+	for k, v := range standard {
+		members[k] = v
+	}
+
+	return json.Marshal(members)
+}
+
+// StatusCoder is implemented by errors that know their HTTP status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Detailer is implemented by errors whose description is safe to show to
+// clients. Only its Detail is exposed for 4xx errors; the message of other
+// errors may include internal context added while wrapping them.
+type Detailer interface {
+	Detail() string
+}
+
+// HandlerFunc is an http handler that returns an error instead of writing
+// it. Use ErrorHandler to turn it into an http.Handler.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ErrorHandler writes errors returned by handlers as problem details.
+//
+// A *Problem in the error chain is written as is. Otherwise the status is
+// taken, in order, from a registered error matched with errors.Is, a
+// StatusCoder in the chain (such as jwt.Error), or
+// *http.MaxBytesError. Anything else is a 500. The detail of 4xx errors comes
+// from a Detailer in the chain and is left empty otherwise. 5xx errors are
+// never detailed to clients; they are logged instead, with the request ID.
+type ErrorHandler struct {
+	logger   *slog.Logger
+	mappings []errorMapping
+}
+
+type errorMapping struct {
+	target error
+	status int
+}
+
+// NewErrorHandler returns an ErrorHandler that logs server errors to logger.
+func NewErrorHandler(logger *slog.Logger) *ErrorHandler {
+	return &ErrorHandler{logger: logger}
+}
+
+// Register maps errors matching target to status. Registrations are checked
+// in order and must happen before the handler serves requests.
+func (h *ErrorHandler) Register(target error, status int) {
+	h.mappings = append(h.mappings, errorMapping{target: target, status: status})
+}
+
+// Handle adapts fn to an http.Handler.
+func (h *ErrorHandler) Handle(fn HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := wrapResponseWriter(w)
+		if err := fn(rw, r); err != nil {
+			h.writeError(rw, r, err)
+		}
+	})
+}
+
+// WriteError writes err as a problem details response.
+func (h *ErrorHandler) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	h.writeError(wrapResponseWriter(w), r, err)
+}
+
+// Problem returns the problem details for err.
+func (h *ErrorHandler) Problem(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		p := *problem
+		if p.Status == 0 {
+			p.Status = http.StatusInternalServerError
+		}
+		if p.Title == "" {
+			p.Title = http.StatusText(p.Status)
+		}
+		return &p
+	}
+
+	status := h.status(err)
+	p := NewProblem(status, "")
+	var detailer Detailer
+	if status < http.StatusInternalServerError && errors.As(err, &detailer) {
+		p.Detail = detailer.Detail()
+	}
+	return p.Wrap(err)
+}
+
+func (h *ErrorHandler) status(err error) int {
+	for _, m := range h.mappings {
+		if errors.Is(err, m.target) {
+			return m.status
+		}
+	}
+
+	var coder StatusCoder
+	if errors.As(err, &coder) {
+		if code := coder.StatusCode(); code >= 400 && code <= 599 {
+			return code
+		}
+	}
+
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusInternalServerError
+}
+
+func (h *ErrorHandler) writeError(w *responseWriter, r *http.Request, err error) {
+	p := h.Problem(err)
+
+	if p.Status >= http.StatusInternalServerError {
+		h.logger.LogAttrs(r.Context(), slog.LevelError, "handler error",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", p.Status),
+			slog.String("error", err.Error()),
+			slog.String("request_id", RequestIDFromContext(r.Context())),
+		)
+	}
+
+	// The handler already started the response; all that's left is the log.
+	if w.WroteHeader() {
+		return
+	}
+
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		ext := make(map[string]any, len(p.Extensions)+1)
+		for k, v := range p.Extensions {
+			ext[k] = v
+		}
+		ext["request_id"] = id
+		p.Extensions = ext
+	}
+
+	body, mErr := json.Marshal(p)
+	if mErr != nil {
+		http.Error(w, http.StatusText(p.Status), p.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type statusError string
+
+func (e statusError) Error() string   { return string(e) }
+func (e statusError) StatusCode() int { return http.StatusUnprocessableEntity }
+func (e statusError) Detail() string  { return string(e) }
+
+var errNotFound = errors.New("account not found")
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+	}{
+		{name: "status coder", err: fmt.Errorf("transfer: %w", statusError("amount cannot be negative")), wantStatus: 422, wantDetail: "amount cannot be negative"},
+		{name: "registered", err: fmt.Errorf("lookup %s: %w", "secret-id", errNotFound), wantStatus: 404},
+		{name: "problem", err: NewProblem(http.StatusConflict, "already exists"), wantStatus: 409, wantDetail: "already exists"},
+		{name: "body too large", err: &http.MaxBytesError{Limit: 1}, wantStatus: 413},
+		{name: "internal", err: errors.New("connection refused"), wantStatus: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			eh := NewErrorHandler(slog.New(slog.NewTextHandler(&buf, nil)))
+			eh.Register(errNotFound, http.StatusNotFound)
+
+			h := RequestID()(eh.Handle(func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/accounts/1", nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("expected content type %s, got %s", ProblemContentType, ct)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body["status"] != float64(tt.wantStatus) {
+				t.Errorf("expected status member %d, got %v", tt.wantStatus, body["status"])
+			}
+			if body["title"] != http.StatusText(tt.wantStatus) {
+				t.Errorf("expected title %q, got %v", http.StatusText(tt.wantStatus), body["title"])
+			}
+			if detail, _ := body["detail"].(string); detail != tt.wantDetail {
+				t.Errorf("expected detail %q, got %q", tt.wantDetail, detail)
+			}
+			if body["instance"] != "/accounts/1" {
+				t.Errorf("expected instance /accounts/1, got %v", body["instance"])
+			}
+			if body["request_id"] != "req-1" {
+				t.Errorf("expected request_id req-1, got %v", body["request_id"])
+			}
+
+			logged := buf.String()
+			if tt.wantStatus >= 500 {
+				if !strings.Contains(logged, "connection refused") || !strings.Contains(logged, "request_id=req-1") {
+					t.Errorf("expected server error to be logged with request ID, got %q", logged)
+				}
+			} else if logged != "" {
+				t.Errorf("expected client error not to be logged, got %q", logged)
+			}
+		})
+	}
+}
+
+func TestErrorHandler_HeaderWritten(t *testing.T) {
+	var buf bytes.Buffer
+	eh := NewErrorHandler(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	h := eh.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		return errors.New("stream broke")
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected original status 202, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected no problem body, got %q", rec.Body.String())
+	}
+	if !strings.Contains(buf.String(), "stream broke") {
+		t.Errorf("expected error to be logged, got %q", buf.String())
+	}
+}
+
+func TestProblem_Extensions(t *testing.T) {
+	p := NewProblem(http.StatusBadRequest, "invalid amount")
+	p.Type = "https://example.com/problems/invalid-amount"
+	p.Extensions = map[string]any{"field": "amount", "status": 999}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("failed to marshal problem: %v", err)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(b, &body); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	if body["field"] != "amount" {
+		t.Errorf("expected extension member, got %v", body)
+	}
+	if body["status"] != float64(http.StatusBadRequest) {
+		t.Errorf("expected standard status to win over extension, got %v", body["status"])
+	}
+}
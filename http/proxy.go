@@ -0,0 +1,285 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Default health check settings of a Proxy.
+const (
+	defaultProxyHealthInterval = 10 * time.Second
+	defaultProxyHealthTimeout  = 2 * time.Second
+	defaultProxyDialTimeout    = 5 * time.Second
+)
+
+// ProxyConfig configures a reverse proxy.
+type ProxyConfig struct {
+	// Upstreams are the base URLs requests are balanced across in
+	// round-robin order, e.g. "http://10.0.0.1:8080".
+	Upstreams []string
+
+	// HealthCheckPath is requested on each upstream every
+	// HealthCheckInterval (10s by default). Upstreams that fail to answer
+	// with a 2xx within HealthCheckTimeout (2s by default) get no traffic
+	// until they pass again. Empty disables health checks.
+	HealthCheckPath     string
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+
+	// StripPrefix is removed from the request path before proxying. It
+	// matches whole path segments: "/api" strips "/api" and "/api/users"
+	// but leaves "/apiary" alone.
+	StripPrefix string
+	// PreserveHost forwards the client's Host header instead of the
+	// upstream's.
+	PreserveHost bool
+
+	// Headers set on or removed from proxied requests and their responses.
+	// X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto are always
+	// set.
+	SetRequestHeaders     map[string]string
+	RemoveRequestHeaders  []string
+	SetResponseHeaders    map[string]string
+	RemoveResponseHeaders []string
+
+	// DialTimeout bounds connecting to an upstream (5s by default).
+	DialTimeout time.Duration
+}
+
+// Proxy is a reverse proxy balancing requests across a pool of upstreams.
+type Proxy struct {
+	cfg       ProxyConfig
+	upstreams []*upstream
+	next      atomic.Uint64
+	proxy     *httputil.ReverseProxy
+	client    *http.Client
+	errors    *ErrorHandler
+	logger    *slog.Logger
+}
+
+type upstream struct {
+	url     *url.URL
+	healthy atomic.Bool
+}
+
+type upstreamKey struct{}
+
+// NewProxy creates a reverse proxy for cfg. Upstream timeouts follow the
+// server config: upstreams must start responding within WriteTimeout, and
+// idle upstream connections are closed after IdleTimeout. Upstreams are
+// healthy until a health check fails; run the checks with Run.
+func NewProxy(cfg ProxyConfig, server Config, logger *slog.Logger) (*Proxy, error) {
+	if len(cfg.Upstreams) == 0 {
+		return nil, errors.New("proxy has no upstreams")
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = defaultProxyHealthInterval
+	}
+	if cfg.HealthCheckTimeout <= 0 {
+		cfg.HealthCheckTimeout = defaultProxyHealthTimeout
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = defaultProxyDialTimeout
+	}
+
+	p := &Proxy{
+		cfg:    cfg,
+		errors: NewErrorHandler(logger),
+		logger: logger,
+	}
+
+	for _, raw := range cfg.Upstreams {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream [%s]", raw)
+		}
+		up := &upstream{url: u}
+		up.healthy.Store(true)
+		p.upstreams = append(p.upstreams, up)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.ResponseHeaderTimeout = server.WriteTimeout
+	transport.IdleConnTimeout = server.IdleTimeout
+
+	p.client = &http.Client{Transport: transport, Timeout: cfg.HealthCheckTimeout}
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite:        p.rewrite,
+		Transport:      transport,
+		FlushInterval:  -1,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.proxyError,
+	}
+
+	return p, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	up := p.pick()
+	if up == nil {
+		p.errors.WriteError(w, r, NewProblem(http.StatusServiceUnavailable, "no healthy upstream"))
+		return
+	}
+	p.proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), upstreamKey{}, up)))
+}
+
+// pick returns the next healthy upstream in round-robin order, or nil if
+// none is healthy.
+func (p *Proxy) pick() *upstream {
+	n := uint64(len(p.upstreams))
+	start := p.next.Add(1) - 1
+	for i := uint64(0); i < n; i++ {
+		up := p.upstreams[(start+i)%n]
+		if up.healthy.Load() {
+			return up
+		}
+	}
+	return nil
+}
+
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	up := pr.In.Context().Value(upstreamKey{}).(*upstream)
+
+	if path, ok := stripPrefix(pr.Out.URL.Path, p.cfg.StripPrefix); ok {
+		pr.Out.URL.Path = path
+		pr.Out.URL.RawPath = ""
+	}
+	pr.SetURL(up.url)
+	pr.SetXForwarded()
+	if p.cfg.PreserveHost {
+		pr.Out.Host = pr.In.Host
+	}
+
+	for _, name := range p.cfg.RemoveRequestHeaders {
+		pr.Out.Header.Del(name)
+	}
+	for name, value := range p.cfg.SetRequestHeaders {
+		pr.Out.Header.Set(name, value)
+	}
+}
+
+// stripPrefix removes prefix from path when it is the whole path or is
+// followed by "/".
+func stripPrefix(path, prefix string) (string, bool) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return path, false
+	}
+	if path == prefix {
+		return "/", true
+	}
+	if rest, ok := strings.CutPrefix(path, prefix); ok && strings.HasPrefix(rest, "/") {
+		return rest, true
+	}
+	return path, false
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	for _, name := range p.cfg.RemoveResponseHeaders {
+		resp.Header.Del(name)
+	}
+	for name, value := range p.cfg.SetResponseHeaders {
+		resp.Header.Set(name, value)
+	}
+	return nil
+}
+
+// proxyError answers with 504 Gateway Timeout when the upstream timed out
+// and 502 Bad Gateway otherwise.
+func (p *Proxy) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	// The client went away; there is no one to answer
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		return
+	}
+
+	status := http.StatusBadGateway
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		status = http.StatusGatewayTimeout
+	}
+
+	up := r.Context().Value(upstreamKey{}).(*upstream)
+	p.errors.WriteError(w, r, NewProblem(status, "").Wrap(fmt.Errorf("proxying to upstream [%s]: %w", up.url.Redacted(), err)))
+}
+
+// Run checks the health of the upstreams every HealthCheckInterval until
+// ctx is cancelled. It returns immediately when health checks are disabled.
+func (p *Proxy) Run(ctx context.Context) {
+	if p.cfg.HealthCheckPath == "" {
+		return
+	}
+
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		p.CheckUpstreams(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckUpstreams runs one health check round and updates the pool.
+func (p *Proxy) CheckUpstreams(ctx context.Context) {
+	for _, up := range p.upstreams {
+		err := p.checkUpstream(ctx, up)
+		healthy := err == nil
+		if up.healthy.Swap(healthy) == healthy {
+			continue
+		}
+
+		if healthy {
+			p.logger.Info("upstream healthy",
+				slog.String("upstream", up.url.Redacted()),
+			)
+		} else {
+			p.logger.Warn("upstream unhealthy",
+				slog.String("upstream", up.url.Redacted()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+func (p *Proxy) checkUpstream(ctx context.Context, up *upstream) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, up.url.JoinPath(p.cfg.HealthCheckPath).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Check returns an error when no upstream is healthy. It can be registered
+// as a readiness check with Health.Register.
+func (p *Proxy) Check(context.Context) error {
+	for _, up := range p.upstreams {
+		if up.healthy.Load() {
+			return nil
+		}
+	}
+	return errors.New("no healthy upstream")
+}
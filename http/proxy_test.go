@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProxy(t *testing.T) {
+	var hits [2]atomic.Int64
+	backend := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i].Add(1)
+			w.Header().Set("Server", "backend")
+			w.Header().Set("X-Path", r.URL.Path)
+			w.Header().Set("X-Internal", r.Header.Get("X-Internal"))
+			w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+			w.Header().Set("X-Forwarded", r.Header.Get("X-Forwarded-For"))
+		}))
+	}
+	a, b := backend(0), backend(1)
+	defer a.Close()
+	defer b.Close()
+
+	p, err := NewProxy(ProxyConfig{
+		Upstreams:             []string{a.URL, b.URL},
+		StripPrefix:           "/api",
+		SetRequestHeaders:     map[string]string{"X-Internal": "edge"},
+		RemoveRequestHeaders:  []string{"Cookie"},
+		RemoveResponseHeaders: []string{"Server"},
+	}, testConfig("127.0.0.1:0"), testLogger())
+	if err != nil {
+		t.Fatalf("failed to create proxy: %v", err)
+	}
+
+	for range 4 {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/42", nil)
+		req.Header.Set("Cookie", "session=secret")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+
+		h := rec.Header()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if h.Get("X-Path") != "/users/42" {
+			t.Errorf("expected prefix to be stripped, got %q", h.Get("X-Path"))
+		}
+		if h.Get("X-Internal") != "edge" || h.Get("X-Cookie") != "" {
+			t.Errorf("expected request headers to be rewritten, got internal=%q cookie=%q", h.Get("X-Internal"), h.Get("X-Cookie"))
+		}
+		if h.Get("X-Forwarded") == "" {
+			t.Error("expected X-Forwarded-For to be set")
+		}
+		if h.Get("Server") != "" {
+			t.Errorf("expected Server header to be removed, got %q", h.Get("Server"))
+		}
+	}
+
+	if hits[0].Load() != 2 || hits[1].Load() != 2 {
+		t.Errorf("expected round-robin across upstreams, got %d and %d", hits[0].Load(), hits[1].Load())
+	}
+}
+
+func TestStripPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   string
+	}{
+		{path: "/api/users", prefix: "/api", want: "/users"},
+		{path: "/api", prefix: "/api", want: "/"},
+		{path: "/api/", prefix: "/api", want: "/"},
+		{path: "/api/users", prefix: "/api/", want: "/users"},
+		{path: "/apiary", prefix: "/api", want: "/apiary"},
+		{path: "/v1/api/users", prefix: "/api", want: "/v1/api/users"},
+		{path: "/users", prefix: "", want: "/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.prefix, func(t *testing.T) {
+			if got, _ := stripPrefix(tt.path, tt.prefix); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProxy_HealthChecks(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "a")
+	}))
+	defer a.Close()
+
+	p, err := NewProxy(ProxyConfig{
+		Upstreams:       []string{a.URL},
+		HealthCheckPath: "/healthz",
+	}, testConfig("127.0.0.1:0"), testLogger())
+	if err != nil {
+		t.Fatalf("failed to create proxy: %v", err)
+	}
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	healthy.Store(false)
+	p.CheckUpstreams(context.Background())
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without healthy upstreams, got %d", code)
+	}
+	if err := p.Check(context.Background()); err == nil {
+		t.Error("expected readiness check to fail")
+	}
+
+	healthy.Store(true)
+	p.CheckUpstreams(context.Background())
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("expected 200 once the upstream recovers, got %d", code)
+	}
+}
+
+func TestProxy_UpstreamErrors(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cfg := testConfig("127.0.0.1:0")
+	cfg.WriteTimeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		upstream string
+		want     int
+	}{
+		{name: "timeout", upstream: slow.URL, want: http.StatusGatewayTimeout},
+		{name: "unreachable", upstream: down.URL, want: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProxy(ProxyConfig{Upstreams: []string{tt.upstream}}, cfg, testLogger())
+			if err != nil {
+				t.Fatalf("failed to create proxy: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("expected problem details, got %s", ct)
+			}
+		})
+	}
+}
+
+func TestNewProxy_InvalidUpstream(t *testing.T) {
+	if _, err := NewProxy(ProxyConfig{}, testConfig(""), testLogger()); err == nil {
+		t.Error("expected error without upstreams")
+	}
+	if _, err := NewProxy(ProxyConfig{Upstreams: []string{"10.0.0.1:8080"}}, testConfig(""), testLogger()); err == nil {
+		t.Error("expected error for upstream without scheme")
+	}
+}
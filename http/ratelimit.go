@@ -0,0 +1,289 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Rejection reasons reported by the limiters.
+const (
+	RejectReasonRateLimit   = "rate_limit"
+	RejectReasonConcurrency = "concurrency"
+)
+
+// bucketSweepInterval is how often idle rate limit buckets are dropped.
+const bucketSweepInterval = time.Minute
+
+// KeyFunc extracts the rate limiting key from a request.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP keys requests by client IP. When trustForwarded is set the first
+// address in X-Forwarded-For is used; only enable it behind a proxy that
+// overwrites the header.
+func KeyByIP(trustForwarded bool) KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + ClientIP(r, trustForwarded)
+	}
+}
+
+// KeyByHeader keys requests by the value of header, such as an API key,
+// falling back to the client IP when it is missing.
+func KeyByHeader(header string, trustForwarded bool) KeyFunc {
+	byIP := KeyByIP(trustForwarded)
+	return func(r *http.Request) string {
+		if v := r.Header.Get(header); v != "" {
+			return "header:" + v
+		}
+		return byIP(r)
+	}
+}
+
+// keyWithFallback keys requests with key, or with fallback when key
+// returns "".
+func keyWithFallback(key, fallback KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		if k := key(r); k != "" {
+			return k
+		}
+		return fallback(r)
+	}
+}
+
+// ParseKeyFunc parses a key spec: "ip" or "header:<name>".
+func ParseKeyFunc(spec string, trustForwarded bool) (KeyFunc, error) {
+	switch {
+	case spec == "" || spec == "ip":
+		return KeyByIP(trustForwarded), nil
+	case strings.HasPrefix(spec, "header:") && len(spec) > len("header:"):
+		return KeyByHeader(strings.TrimPrefix(spec, "header:"), trustForwarded), nil
+	}
+	return nil, fmt.Errorf("invalid rate limit key [%s]", spec)
+}
+
+// ClientIP returns the client IP of r.
+func ClientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is a per-key token bucket limiter. Rejected requests receive
+// 429 Too Many Requests with a Retry-After header.
+type RateLimiter struct {
+	// OnReject, when set, is called with RejectReasonRateLimit for each
+	// rejected request, e.g. Server.RecordRejection.
+	OnReject func(reason string)
+
+	rate  float64
+	burst float64
+	key   KeyFunc
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+
+	rejected atomic.Uint64
+}
+
+// NewRateLimiter creates a limiter allowing rps requests per second per key
+// with bursts of up to burst requests. A burst below 1 defaults to rps
+// rounded up.
+func NewRateLimiter(rps float64, burst int, key KeyFunc) *RateLimiter {
+	b := float64(burst)
+	if b < 1 {
+		b = math.Max(1, math.Ceil(rps))
+	}
+
+	return &RateLimiter{
+		rate:    rps,
+		burst:   b,
+		key:     key,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow consumes a token for key. When no token is available it returns
+// false and how long until one is.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have refilled completely, since they behave the
+// same as a new bucket.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// Middleware rejects requests exceeding the rate for their key.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.Allow(l.key(r)); !ok {
+			l.rejected.Add(1)
+			if l.OnReject != nil {
+				l.OnReject(RejectReasonRateLimit)
+			}
+			reject(w, http.StatusTooManyRequests, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Rejected returns the number of requests rejected by the limiter.
+func (l *RateLimiter) Rejected() uint64 {
+	return l.rejected.Load()
+}
+
+// ConcurrencyLimiter bounds the number of requests handled at once. Requests
+// over the limit wait up to the queue timeout for a slot before receiving
+// 503 Service Unavailable with a Retry-After header.
+type ConcurrencyLimiter struct {
+	// OnReject, when set, is called with RejectReasonConcurrency for each
+	// rejected request, e.g. Server.RecordRejection.
+	OnReject func(reason string)
+
+	slots        chan struct{}
+	queueTimeout time.Duration
+
+	rejected atomic.Uint64
+}
+
+// NewConcurrencyLimiter creates a limiter allowing max requests in flight.
+func NewConcurrencyLimiter(max int, queueTimeout time.Duration) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		slots:        make(chan struct{}, max),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Middleware rejects requests that can't get a slot within the queue timeout.
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r) {
+			l.rejected.Add(1)
+			if l.OnReject != nil {
+				l.OnReject(RejectReasonConcurrency)
+			}
+			reject(w, http.StatusServiceUnavailable, time.Second)
+			return
+		}
+		defer func() { <-l.slots }()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *ConcurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// Rejected returns the number of requests rejected by the limiter.
+func (l *ConcurrencyLimiter) Rejected() uint64 {
+	return l.rejected.Load()
+}
+
+// limiters are the limiters a server built and the config they were built
+// from, so a reload that leaves their settings alone keeps their state.
+type limiters struct {
+	rate              *RateLimiter
+	rateConfig        Config
+	concurrency       *ConcurrencyLimiter
+	concurrencyConfig Config
+}
+
+func sameRateLimit(a, b Config) bool {
+	return a.RateLimitRPS == b.RateLimitRPS &&
+		a.RateLimitBurst == b.RateLimitBurst &&
+		a.RateLimitKey == b.RateLimitKey &&
+		a.RateLimitTrustForwarded == b.RateLimitTrustForwarded
+}
+
+func sameConcurrencyLimit(a, b Config) bool {
+	return a.MaxInFlight == b.MaxInFlight &&
+		a.MaxInFlightQueueTimeout == b.MaxInFlightQueueTimeout
+}
+
+func reject(w http.ResponseWriter, code int, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(2, 2, KeyByIP(false))
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("expected request %d within burst to be allowed", i+1)
+		}
+	}
+
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("expected request over burst to be rejected")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("expected wait of 500ms, got %v", wait)
+	}
+
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("expected other key to have its own bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("expected token to be refilled")
+	}
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(1, 1, KeyByIP(false))
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	now = now.Add(bucketSweepInterval)
+	l.Allow("b")
+
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("expected refilled bucket to be swept")
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	l := NewRateLimiter(1, 1, KeyByIP(false))
+	var reasons []string
+	l.OnReject = func(reason string) { reasons = append(reasons, reason) }
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After 1, got %q", rec.Header().Get("Retry-After"))
+	}
+	if l.Rejected() != 1 {
+		t.Errorf("expected 1 rejection, got %d", l.Rejected())
+	}
+	if len(reasons) != 1 || reasons[0] != RejectReasonRateLimit {
+		t.Errorf("expected OnReject with %q, got %v", RejectReasonRateLimit, reasons)
+	}
+}
+
+type testUserKey struct{}
+
+func TestServer_SetRateLimitKey(t *testing.T) {
+	cfg := testConfig("127.0.0.1:0")
+	cfg.RateLimitRPS = 1
+	cfg.RateLimitBurst = 1
+	srv := NewServerWithConfig("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), cfg, testLogger())
+
+	// Stands in for an authentication middleware storing verified claims
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-User"); user != "" {
+				r = r.WithContext(context.WithValue(r.Context(), testUserKey{}, user))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	srv.SetRateLimitKey(func(r *http.Request) string {
+		if user, ok := r.Context().Value(testUserKey{}).(string); ok {
+			return "user:" + user
+		}
+		return ""
+	}, authenticate)
+
+	tests := []struct {
+		name string
+		user string
+		want int
+	}{
+		{name: "first user", user: "a", want: http.StatusOK},
+		{name: "second user from the same ip", user: "b", want: http.StatusOK},
+		{name: "first user again", user: "a", want: http.StatusTooManyRequests},
+		{name: "anonymous falls back to ip", want: http.StatusOK},
+		{name: "anonymous again", want: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			if tt.user != "" {
+				req.Header.Set("X-User", tt.user)
+			}
+
+			rec := httptest.NewRecorder()
+			srv.server.Handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		trust   bool
+		headers map[string]string
+		want    string
+	}{
+		{name: "ip", spec: "ip", want: "ip:10.0.0.1"},
+		{name: "forwarded ignored", spec: "ip", headers: map[string]string{"X-Forwarded-For": "1.2.3.4"}, want: "ip:10.0.0.1"},
+		{name: "forwarded trusted", spec: "ip", trust: true, headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 10.0.0.2"}, want: "ip:1.2.3.4"},
+		{name: "header", spec: "header:X-API-Key", headers: map[string]string{"X-API-Key": "k1"}, want: "header:k1"},
+		{name: "header missing", spec: "header:X-API-Key", want: "ip:10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKeyFunc(tt.spec, tt.trust)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if got := key(req); got != tt.want {
+				t.Errorf("expected key %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := ParseKeyFunc("cookie", false); err == nil {
+		t.Error("expected error for invalid key spec")
+	}
+}
+
+func TestConcurrencyLimiter_Middleware(t *testing.T) {
+	l := NewConcurrencyLimiter(1, 10*time.Millisecond)
+
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(entered)
+			<-release
+		}
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+	if l.Rejected() != 1 {
+		t.Errorf("expected 1 rejection, got %d", l.Rejected())
+	}
+
+	close(release)
+	wg.Wait()
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 once a slot is free, got %d", rec.Code)
+	}
+}
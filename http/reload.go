@@ -0,0 +1,383 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWatchInterval is how often a WatchedFileSource checks its file when
+// no interval is set.
+const defaultWatchInterval = 5 * time.Second
+
+// ConfigSource loads the server config.
+type ConfigSource interface {
+	Load() (Config, error)
+}
+
+// ConfigWatcher is implemented by sources that detect their own changes.
+// Watch calls changed after each change until ctx is done.
+type ConfigWatcher interface {
+	Watch(ctx context.Context, changed func())
+}
+
+// EnvSource loads the config from environment variables with Prefix.
+type EnvSource struct {
+	Prefix string
+}
+
+func (s EnvSource) Load() (Config, error) {
+	return LoadConfig(s.Prefix)
+}
+
+// FileSource loads the config from a JSON or YAML file at Path, on top of
+// the environment config with Prefix. Values set in the file take precedence
+// over the environment; unknown keys are an error.
+type FileSource struct {
+	Path   string
+	Prefix string
+}
+
+func (s FileSource) Load() (Config, error) {
+	cfg, err := LoadConfig(s.Prefix)
+	if err != nil {
+		return Config{}, err
+	}
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config file [%s]: %w", s.Path, err)
+	}
+
+	// JSON is valid YAML, so both formats go through the YAML decoder
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("parsing config file [%s]: %w", s.Path, err)
+	}
+
+	return cfg, nil
+}
+
+// WatchedFileSource is a FileSource that reports changes made to its file
+// since it was last loaded. The file is polled every Interval (5s by
+// default).
+type WatchedFileSource struct {
+	FileSource
+	Interval time.Duration
+
+	mu     sync.Mutex
+	loaded os.FileInfo
+}
+
+// NewWatchedFileSource returns a source for the file at path that is polled
+// every interval.
+func NewWatchedFileSource(path, prefix string, interval time.Duration) *WatchedFileSource {
+	return &WatchedFileSource{
+		FileSource: FileSource{Path: path, Prefix: prefix},
+		Interval:   interval,
+	}
+}
+
+func (s *WatchedFileSource) Load() (Config, error) {
+	// Stat first so a write racing with the read is seen as a change
+	info, statErr := os.Stat(s.Path)
+
+	cfg, err := s.FileSource.Load()
+	if err != nil {
+		return Config{}, err
+	}
+
+	if statErr == nil {
+		s.mu.Lock()
+		s.loaded = info
+		s.mu.Unlock()
+	}
+	return cfg, nil
+}
+
+func (s *WatchedFileSource) Watch(ctx context.Context, changed func()) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(s.Path)
+		if err != nil {
+			// Editors may replace the file; wait for it to reappear
+			continue
+		}
+
+		s.mu.Lock()
+		modified := s.loaded == nil || !info.ModTime().Equal(s.loaded.ModTime()) || info.Size() != s.loaded.Size()
+		if modified {
+			// Report each change once, even if loading it fails
+			s.loaded = info
+		}
+		s.mu.Unlock()
+
+		if modified {
+			changed()
+		}
+	}
+}
+
+// ConfigChange is a field that differs between two configs. Field is the
+// key used in config files.
+type ConfigChange struct {
+	Field           string
+	Old             any
+	New             any
+	RestartRequired bool
+}
+
+// DiffConfig returns the fields that differ between from and to.
+func DiffConfig(from, to Config) []ConfigChange {
+	var changes []ConfigChange
+
+	fv, tv := reflect.ValueOf(from), reflect.ValueOf(to)
+	t := fv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		oldValue, newValue := fv.Field(i).Interface(), tv.Field(i).Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		field := t.Field(i)
+		changes = append(changes, ConfigChange{
+			Field:           field.Tag.Get("yaml"),
+			Old:             oldValue,
+			New:             newValue,
+			RestartRequired: field.Tag.Get("reload") == "restart",
+		})
+	}
+
+	return changes
+}
+
+// withRestartFields returns cfg with the fields that require a restart
+// copied from running.
+func withRestartFields(running, cfg Config) Config {
+	rv, cv := reflect.ValueOf(running), reflect.ValueOf(&cfg).Elem()
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("reload") == "restart" {
+			cv.Field(i).Set(rv.Field(i))
+		}
+	}
+	return cfg
+}
+
+// NewServerFromSource creates a server from the config loaded from source.
+// Run reloads the config from source on SIGHUP and, for a
+// *WatchedFileSource, when the file changes.
+func NewServerFromSource(name string, handler http.Handler, source ConfigSource, logger *slog.Logger) (*Server, error) {
+	cfg, err := source.Load()
+	if err != nil {
+		logger.Error("failed to load config",
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	s := NewServerWithConfig(name, handler, cfg, logger)
+	s.source = source
+	return s, nil
+}
+
+// Config returns the config currently applied to the server.
+func (s *Server) Config() Config {
+	return *s.current.Load()
+}
+
+// Reload loads the config from the server's source and applies it.
+func (s *Server) Reload() ([]ConfigChange, error) {
+	if s.source == nil {
+		return nil, errors.New("server has no config source")
+	}
+
+	cfg, err := s.source.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload config: %w", err)
+	}
+
+	return s.ApplyConfig(cfg)
+}
+
+// ApplyConfig applies cfg to the running server and logs the changes.
+// Timeouts, middleware settings, limits and the log level take effect for
+// new requests; fields tagged reload:"restart" keep their current value and
+// are reported as requiring a restart. A config with an invalid log level,
+// compression, security headers, CORS or rate limit setting is rejected as
+// a whole and the running config is kept. Rate and concurrency limiters
+// keep their state unless their own settings change.
+func (s *Server) ApplyConfig(cfg Config) ([]ConfigChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := s.Config()
+	changes := DiffConfig(running, cfg)
+	if len(changes) == 0 {
+		s.logger.Info("config reloaded without changes")
+		return nil, nil
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config, keeping the running config: %w", err)
+	}
+
+	next := withRestartFields(running, cfg)
+	s.setLogLevel(next.LogLevel)
+	s.current.Store(&next)
+	h := s.wrapHandler(s.base, next)
+	s.handler.Store(&h)
+
+	var applied, restart []any
+	for _, c := range changes {
+		attr := slog.Group(c.Field, slog.Any("old", c.Old), slog.Any("new", c.New))
+		if c.RestartRequired {
+			restart = append(restart, attr)
+		} else {
+			applied = append(applied, attr)
+		}
+	}
+
+	level := slog.LevelInfo
+	if len(restart) > 0 {
+		level = slog.LevelWarn
+	}
+	s.logger.Log(context.Background(), level, "config reloaded",
+		slog.Group("applied", applied...),
+		slog.Group("restart_required", restart...),
+	)
+
+	return changes, nil
+}
+
+// watchConfig reloads the config from the server's source on the reload
+// signals and, for sources that support it, when the source changes.
+func (s *Server) watchConfig(ctx context.Context) {
+	if s.source == nil {
+		return
+	}
+
+	changed := make(chan struct{}, 1)
+	if w, ok := s.source.(ConfigWatcher); ok {
+		go w.Watch(ctx, func() {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+	}
+
+	var sigCh chan os.Signal
+	if len(reloadSignals) > 0 {
+		sigCh = make(chan os.Signal, 1)
+		signal.Notify(sigCh, reloadSignals...)
+		defer signal.Stop(sigCh)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+		case <-changed:
+		}
+
+		if _, err := s.Reload(); err != nil {
+			s.logger.Error("failed to reload config",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// noLevel lets every record through to the wrapped handler.
+const noLevel = slog.Level(math.MinInt)
+
+// setLogLevel sets the minimum level of the server's logs. Invalid levels
+// are logged and leave the level unchanged.
+func (s *Server) setLogLevel(level string) {
+	if level == "" {
+		s.logLevel.Set(noLevel)
+		return
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		s.logger.Warn("invalid log level, keeping the current level",
+			slog.String("log_level", level),
+		)
+		return
+	}
+	s.logLevel.Set(l)
+}
+
+// levelHandler drops records below a level that can change at runtime.
+type levelHandler struct {
+	slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// reloadedTimeouts applies the read and write timeouts of cfg where they
+// differ from startup. http.Server can't be reconfigured while serving, so
+// they are set as deadlines on each request instead.
+func reloadedTimeouts(startup, cfg Config) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			now := time.Now()
+			if cfg.ReadTimeout != startup.ReadTimeout {
+				_ = rc.SetReadDeadline(deadline(now, cfg.ReadTimeout))
+			}
+			if cfg.WriteTimeout != startup.WriteTimeout {
+				_ = rc.SetWriteDeadline(deadline(now, cfg.WriteTimeout))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deadline returns now+d, or no deadline when d is not positive.
+func deadline(now time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return time.Time{}
+	}
+	return now.Add(d)
+}
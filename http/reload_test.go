@@ -0,0 +1,273 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestFileSource(t *testing.T) {
+	t.Setenv("GOXRELOAD_ADDRESS", "127.0.0.1:9000")
+	t.Setenv("GOXRELOAD_WRITE_TIMEOUT", "5s")
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "yaml", file: "http.yaml", content: "write_timeout: 30s\naccess_log: true\ncors_allowed_origins:\n  - https://a.example.com\n"},
+		{name: "json", file: "http.json", content: `{"write_timeout": "30s", "access_log": true, "cors_allowed_origins": ["https://a.example.com"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := FileSource{Path: writeConfigFile(t, tt.file, tt.content), Prefix: "GOXRELOAD"}
+
+			cfg, err := src.Load()
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+
+			if cfg.Address != "127.0.0.1:9000" {
+				t.Errorf("expected address from env, got %s", cfg.Address)
+			}
+			if cfg.WriteTimeout != 30*time.Second {
+				t.Errorf("expected file to override env write timeout, got %v", cfg.WriteTimeout)
+			}
+			if !cfg.AccessLog {
+				t.Error("expected access log enabled by file")
+			}
+			if cfg.ReadHeaderTimeout != 60*time.Second {
+				t.Error("expected defaults for fields not in the file")
+			}
+			if len(cfg.CORSAllowedOrigins) != 1 {
+				t.Errorf("expected one CORS origin, got %v", cfg.CORSAllowedOrigins)
+			}
+		})
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		src := FileSource{Path: writeConfigFile(t, "http.yaml", "write_timeot: 30s\n"), Prefix: "GOXRELOAD"}
+		if _, err := src.Load(); err == nil {
+			t.Fatal("expected error for unknown key")
+		}
+	})
+}
+
+func TestDiffConfig(t *testing.T) {
+	from := testConfig("127.0.0.1:0")
+	to := from
+	to.Address = "127.0.0.1:8080"
+	to.WriteTimeout = 30 * time.Second
+
+	changes := DiffConfig(from, to)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+
+	want := map[string]bool{"address": true, "write_timeout": false}
+	for _, c := range changes {
+		restart, ok := want[c.Field]
+		if !ok {
+			t.Errorf("unexpected change %s", c.Field)
+			continue
+		}
+		if c.RestartRequired != restart {
+			t.Errorf("expected %s restart required=%v, got %v", c.Field, restart, c.RestartRequired)
+		}
+	}
+}
+
+func TestServerApplyConfig(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	cfg := testConfig("127.0.0.1:0")
+	cfg.AccessLog = true
+	srv := NewServerWithConfig("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), cfg, logger)
+
+	post := func() int {
+		rec := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+		return rec.Code
+	}
+	if code := post(); code != http.StatusOK {
+		t.Fatalf("expected status 200 before reload, got %d", code)
+	}
+
+	next := cfg
+	next.MaxRequestBodyBytes = 4
+	next.LogLevel = "warn"
+	next.Address = "127.0.0.1:8080"
+	buf.Reset()
+
+	changes, err := srv.ApplyConfig(next)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+
+	logged := buf.String()
+	if !strings.Contains(logged, "config reloaded") ||
+		!strings.Contains(logged, "applied.max_request_body_bytes.new=4") ||
+		!strings.Contains(logged, "restart_required.address.new=127.0.0.1:8080") {
+		t.Errorf("expected diff in reload log, got %q", logged)
+	}
+
+	if srv.Config().Address != cfg.Address {
+		t.Errorf("expected address to keep its running value, got %s", srv.Config().Address)
+	}
+	if srv.Config().MaxRequestBodyBytes != 4 {
+		t.Errorf("expected body limit to be applied, got %d", srv.Config().MaxRequestBodyBytes)
+	}
+
+	buf.Reset()
+	if code := post(); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413 after reload, got %d", code)
+	}
+	if strings.Contains(buf.String(), "http request") {
+		t.Errorf("expected info access log to be dropped at warn level, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "request body too large") {
+		t.Errorf("expected warn log to pass at warn level, got %q", buf.String())
+	}
+}
+
+func TestServerApplyConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{name: "log level", modify: func(c *Config) { c.LogLevel = "verbose" }},
+		{name: "compression encoding", modify: func(c *Config) {
+			c.Compression = true
+			c.CompressionEncodings = []string{"lzma"}
+		}},
+		{name: "security headers profile", modify: func(c *Config) { c.SecurityHeaders = "paranoid" }},
+		{name: "cors any origin with credentials", modify: func(c *Config) {
+			c.CORSAllowedOrigins = []string{"*"}
+			c.CORSAllowCredentials = true
+		}},
+		{name: "rate limit key", modify: func(c *Config) {
+			c.RateLimitRPS = 1
+			c.RateLimitKey = "cookie"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig("127.0.0.1:0")
+			srv := NewServerWithConfig("test", http.NotFoundHandler(), cfg, testLogger())
+
+			next := cfg
+			next.MaxRequestBodyBytes = 4
+			tt.modify(&next)
+
+			changes, err := srv.ApplyConfig(next)
+			if err == nil {
+				t.Fatal("expected error for invalid config")
+			}
+			if changes != nil {
+				t.Errorf("expected no changes, got %+v", changes)
+			}
+			if srv.Config().MaxRequestBodyBytes != 0 {
+				t.Error("expected the running config to be kept")
+			}
+		})
+	}
+}
+
+func TestServerApplyConfig_KeepsLimiters(t *testing.T) {
+	cfg := testConfig("127.0.0.1:0")
+	cfg.RateLimitRPS = 0.001
+	cfg.RateLimitBurst = 1
+	srv := NewServerWithConfig("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), cfg, testLogger())
+
+	get := func() int {
+		rec := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+	apply := func(cfg Config) {
+		if _, err := srv.ApplyConfig(cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+
+	next := cfg
+	next.LogLevel = "warn"
+	apply(next)
+	if code := get(); code != http.StatusTooManyRequests {
+		t.Errorf("expected the rate limit state to survive an unrelated change, got status %d", code)
+	}
+
+	next.RateLimitBurst = 2
+	apply(next)
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected a new limiter after its settings changed, got status %d", code)
+	}
+}
+
+func TestServerReload_WatchedFile(t *testing.T) {
+	path := writeConfigFile(t, "http.yaml", "address: 127.0.0.1:0\nmax_request_body_bytes: 0\n")
+	src := NewWatchedFileSource(path, "GOXRELOAD", 10*time.Millisecond)
+
+	srv, err := NewServerFromSource("test", http.NotFoundHandler(), src, testLogger())
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-srv.Ready()
+
+	// Make sure the new content gets a different mtime
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("address: 127.0.0.1:0\nmax_request_body_bytes: 1024\n"), 0o600); err != nil {
+		t.Fatalf("failed to update config file: %v", err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("failed to touch config file: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.Config().MaxRequestBodyBytes != 1024 {
+		if time.Now().After(deadline) {
+			t.Fatal("config was not reloaded after the file changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerReload_NoSource(t *testing.T) {
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), testConfig("127.0.0.1:0"), testLogger())
+	if _, err := srv.Reload(); err == nil {
+		t.Fatal("expected error reloading a server without a source")
+	}
+}
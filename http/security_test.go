@@ -0,0 +1,144 @@
+package http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  SecurityHeadersPolicy
+		tls     bool
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "basic",
+			policy: SecurityHeadersPolicy{Profile: SecurityProfileBasic},
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "SAMEORIGIN",
+				"Strict-Transport-Security": "",
+			},
+		},
+		{
+			name:   "strict over tls",
+			policy: SecurityHeadersPolicy{Profile: SecurityProfileStrict},
+			tls:    true,
+			want: map[string]string{
+				"X-Frame-Options":           "DENY",
+				"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+				"Strict-Transport-Security": "max-age=63072000; includeSubDomains",
+			},
+		},
+		{
+			name:   "strict without tls",
+			policy: SecurityHeadersPolicy{Profile: SecurityProfileStrict},
+			want:   map[string]string{"Strict-Transport-Security": ""},
+		},
+		{
+			name:   "csp override and hsts",
+			policy: SecurityHeadersPolicy{Profile: SecurityProfileBasic, ContentSecurityPolicy: "default-src 'self'", HSTSMaxAge: time.Hour},
+			tls:    true,
+			want: map[string]string{
+				"Content-Security-Policy":   "default-src 'self'",
+				"Strict-Transport-Security": "max-age=3600; includeSubDomains",
+			},
+		},
+		{
+			name:    "unknown profile",
+			policy:  SecurityHeadersPolicy{Profile: "paranoid"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := SecurityHeaders(tt.policy)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			rec := httptest.NewRecorder()
+			mw(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+			for k, v := range tt.want {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("expected %s %q, got %q", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	called := false
+	h := MaxBodySize(4, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	t.Run("content length over limit", func(t *testing.T) {
+		buf.Reset()
+		called = false
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413, got %d", rec.Code)
+		}
+		if called {
+			t.Error("expected handler not to run")
+		}
+		if !strings.Contains(buf.String(), "request body too large") {
+			t.Errorf("expected rejection to be logged, got %q", buf.String())
+		}
+	})
+
+	t.Run("streamed body over limit", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("too large")))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413, got %d", rec.Code)
+		}
+		if !strings.Contains(buf.String(), "request body too large") {
+			t.Errorf("expected rejection to be logged, got %q", buf.String())
+		}
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("ok")))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rec.Code)
+		}
+	})
+}
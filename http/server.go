@@ -2,14 +2,18 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
+	"slices"
 	"strings"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/quic-go/quic-go/http3"
 )
 
 // Server wraps http.Server with additional functionality
@@ -17,24 +21,209 @@ type Server struct {
 	server *http.Server
 	logger *slog.Logger
 	name   string
-	config Config
+	config Config // as started; see Config for the applied one
+	health *Health
+
+	// Handler and config applied at runtime by ApplyConfig
+	base     http.Handler
+	handler  atomic.Pointer[http.Handler]
+	current  atomic.Pointer[Config]
+	logLevel *slog.LevelVar
+	source   ConfigSource
+
+	// Set by SetRateLimitKey before the server starts
+	rateLimitKey KeyFunc
+	authenticate Middleware
+
+	// Limiters built by wrapHandler; guarded by mu after construction
+	limits limiters
+
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// Shutdown state; hijacked is guarded by mu
+	draining  context.Context
+	stopDrain context.CancelFunc
+	inFlight  atomic.Int64
+	hijacked  map[*hijackedConn]struct{}
+
+	mu        sync.Mutex
+	listener  net.Listener
+	http3     *http3.Server
+	http3Conn net.PacketConn
 }
 
 // NewServer creates a new HTTP server
 func NewServerWithConfig(name string, handler http.Handler, cfg Config, logger *slog.Logger) *Server {
-	return &Server{
+	logLevel := new(slog.LevelVar)
+	logger = slog.New(&levelHandler{Handler: logger.Handler(), level: logLevel})
+
+	s := &Server{
 		server: &http.Server{
-			Handler:           handler,
 			Addr:              cfg.Address,
 			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
 			ReadTimeout:       cfg.ReadTimeout,
 			WriteTimeout:      cfg.WriteTimeout,
 			IdleTimeout:       cfg.IdleTimeout,
 		},
-		logger: logger.With(slog.String("server", name)),
-		name:   name,
-		config: cfg,
+		logger:   logger.With(slog.String("server", name)),
+		name:     name,
+		config:   cfg,
+		health:   NewHealth(),
+		ready:    make(chan struct{}),
+		hijacked: make(map[*hijackedConn]struct{}),
+		base:     handler,
+		logLevel: logLevel,
 	}
+	s.draining, s.stopDrain = context.WithCancel(context.Background())
+	s.setLogLevel(cfg.LogLevel)
+	s.current.Store(&cfg)
+	h := s.wrapHandler(handler, cfg)
+	s.handler.Store(&h)
+	s.server.Handler = http.HandlerFunc(s.serveHTTP)
+	s.configureProtocols()
+
+	return s
+}
+
+// serveHTTP dispatches to the handler built from the applied config and
+// tracks the request until it completes.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	r = r.WithContext(context.WithValue(r.Context(), drainingKey{}, s.draining))
+	(*s.handler.Load()).ServeHTTP(&drainWriter{ResponseWriter: w, server: s}, r)
+}
+
+// wrapHandler applies the middleware enabled in cfg. Health endpoints are
+// served before metrics and the access log so probes don't flood them.
+// Compression runs inside both so they report the bytes sent.
+func (s *Server) wrapHandler(handler http.Handler, cfg Config) http.Handler {
+	var middlewares []Middleware
+	if s.config.HTTP2MaxHeaderListSize > 0 {
+		middlewares = append(middlewares, maxHeaderListSize(s.config.HTTP2MaxHeaderListSize))
+	}
+	if cfg.ReadTimeout != s.config.ReadTimeout || cfg.WriteTimeout != s.config.WriteTimeout {
+		middlewares = append(middlewares, reloadedTimeouts(s.config, cfg))
+	}
+	if cfg.RequestID {
+		middlewares = append(middlewares, RequestID())
+	}
+	if cfg.HealthEndpoints {
+		middlewares = append(middlewares, s.health.Handler)
+	}
+	if cfg.Tracing {
+		middlewares = append(middlewares, Tracing(s.name, nil))
+	}
+	if cfg.Metrics {
+		middlewares = append(middlewares, defaultServerMetrics().Middleware(s.name))
+	}
+	if cfg.AccessLog {
+		middlewares = append(middlewares, AccessLog(s.logger))
+	}
+	if cfg.RecoverPanics {
+		middlewares = append(middlewares, Recover(s.logger))
+	}
+	if cfg.Compression {
+		compress, err := Compress(compressionPolicy(cfg))
+		if err != nil {
+			s.logger.Warn("invalid compression encoding, not compressing responses",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			middlewares = append(middlewares, compress)
+		}
+	}
+	if securityHeadersEnabled(cfg) {
+		security, err := SecurityHeaders(securityHeadersPolicy(cfg))
+		if err != nil {
+			s.logger.Warn("invalid security headers profile, not setting security headers",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			middlewares = append(middlewares, security)
+		}
+	}
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		cors, err := CORS(corsPolicy(cfg))
+		if err != nil {
+			s.logger.Warn("invalid cors policy, not allowing cross-origin requests",
+				slog.String("error", err.Error()),
+			)
+		} else {
+			middlewares = append(middlewares, cors)
+		}
+	}
+	if cfg.MaxRequestBodyBytes > 0 {
+		middlewares = append(middlewares, MaxBodySize(cfg.MaxRequestBodyBytes, s.logger))
+	}
+	if s.authenticate != nil {
+		middlewares = append(middlewares, s.authenticate)
+	}
+	if cfg.RateLimitRPS > 0 {
+		middlewares = append(middlewares, s.rateLimiter(cfg).Middleware)
+	}
+	if cfg.MaxInFlight > 0 {
+		middlewares = append(middlewares, s.concurrencyLimiter(cfg).Middleware)
+	}
+
+	return Chain(handler, middlewares...)
+}
+
+// SetRateLimitKey makes the rate limiter key requests with key, falling
+// back to RATE_LIMIT_KEY when key returns "". When authenticate is not nil
+// it runs right before the limiter, so key can read verified claims from
+// the request context; pass an authentication middleware that lets
+// anonymous requests through, such as jwt.Authenticate with
+// AuthConfig.Optional. It must be called before the server starts.
+func (s *Server) SetRateLimitKey(key KeyFunc, authenticate Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimitKey = key
+	s.authenticate = authenticate
+	s.limits.rate = nil
+	h := s.wrapHandler(s.base, s.Config())
+	s.handler.Store(&h)
+}
+
+// rateLimiter returns the rate limiter for cfg. The previous limiter is
+// kept, with its buckets, while its settings don't change.
+func (s *Server) rateLimiter(cfg Config) *RateLimiter {
+	if s.limits.rate != nil && sameRateLimit(s.limits.rateConfig, cfg) {
+		return s.limits.rate
+	}
+
+	key, err := ParseKeyFunc(cfg.RateLimitKey, cfg.RateLimitTrustForwarded)
+	if err != nil {
+		s.logger.Warn("invalid rate limit key, limiting by client ip",
+			slog.String("error", err.Error()),
+		)
+		key = KeyByIP(cfg.RateLimitTrustForwarded)
+	}
+	if s.rateLimitKey != nil {
+		key = keyWithFallback(s.rateLimitKey, key)
+	}
+
+	limiter := NewRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst, key)
+	limiter.OnReject = s.RecordRejection
+	s.limits.rate, s.limits.rateConfig = limiter, cfg
+	return limiter
+}
+
+// concurrencyLimiter returns the concurrency limiter for cfg. The previous
+// limiter is kept while its settings don't change, so requests in flight
+// keep counting against the limit.
+func (s *Server) concurrencyLimiter(cfg Config) *ConcurrencyLimiter {
+	if s.limits.concurrency != nil && sameConcurrencyLimit(s.limits.concurrencyConfig, cfg) {
+		return s.limits.concurrency
+	}
+
+	limiter := NewConcurrencyLimiter(cfg.MaxInFlight, cfg.MaxInFlightQueueTimeout)
+	limiter.OnReject = s.RecordRejection
+	s.limits.concurrency, s.limits.concurrencyConfig = limiter, cfg
+	return limiter
 }
 
 func NewServer(name string, handler http.Handler, logger *slog.Logger) (*Server, error) {
@@ -48,62 +237,190 @@ func NewServer(name string, handler http.Handler, logger *slog.Logger) (*Server,
 	return NewServerWithConfig(name, handler, cfg, logger), nil
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server on the configured address. Besides TCP
+// addresses it accepts "unix:/path/to.sock" and "systemd:[name]" for
+// systemd socket activation. When the process was started by Upgrade, the
+// listener inherited from the previous process is used instead.
 func (s *Server) Start() error {
+	// Reuse the listener handed over by the previous process on upgrade
+	if ln, ok := inheritedListener(s.server.Addr); ok {
+		return s.StartWithListener(ln)
+	}
+
+	ln, err := Listen(s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+
+	return s.StartWithListener(ln)
+}
+
+// StartWithListener starts the HTTP server on an already bound listener.
+// The listener is closed when the server shuts down.
+func (s *Server) StartWithListener(ln net.Listener) error {
+	if s.config.TLSEnabled() {
+		tlsCfg, err := s.config.tlsConfig(s.logger)
+		if err != nil {
+			ln.Close()
+			return fmt.Errorf("failed to configure tls: %w", err)
+		}
+		s.server.TLSConfig = tlsCfg
+	}
+
+	if s.config.HTTP3 {
+		if s.server.TLSConfig == nil {
+			ln.Close()
+			return fmt.Errorf("failed to start http3: tls is not configured")
+		}
+		if err := s.startHTTP3(ln, s.server.TLSConfig); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	return s.serve(ln)
+}
+
+// serve accepts connections on ln and marks the server ready once the
+// listener is bound.
+func (s *Server) serve(ln net.Listener) error {
 	s.logger.Info("starting server",
-		slog.String("address", s.server.Addr),
+		slog.String("address", listenerAddress(ln)),
 		slog.Duration("read_timeout", s.server.ReadTimeout),
 		slog.Duration("write_timeout", s.server.WriteTimeout),
+		slog.Bool("tls", s.server.TLSConfig != nil),
 	)
 
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	s.readyOnce.Do(func() { close(s.ready) })
+
+	var err error
+	if s.server.TLSConfig != nil {
+		// Certificates are provided by TLSConfig.GetCertificate
+		err = s.server.ServeTLS(ln, "", "")
+	} else {
+		err = s.server.Serve(ln)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
 	return nil
 }
 
+// RecordRejection counts a request rejected by a limiter in the server's
+// metrics when they are enabled. Assign it to the OnReject field of limiters
+// built with NewRateLimiter or NewConcurrencyLimiter.
+func (s *Server) RecordRejection(reason string) {
+	if s.Config().Metrics {
+		defaultServerMetrics().RecordRejection(s.name, reason)
+	}
+}
+
+// Ready returns a channel that is closed once the server's listener is bound
+// and accepting connections.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Name returns the server name
+func (s *Server) Name() string {
+	return s.name
+}
+
 // StartWithGracefulShutdown starts the server and handles graceful shutdown
+// on SIGINT/SIGTERM. On SIGUSR2, or SIGHUP when the server has no config
+// source to reload, the listener is handed to a new instance of the
+// executable (see Upgrade) and the server drains once it is ready.
 func (s *Server) StartWithGracefulShutdown() error {
-	// Start server in a goroutine
+	return runWithSignals(s.logger, s.Run, s.Upgrade, s.source != nil)
+}
+
+// Run starts the server and blocks until ctx is cancelled or the server fails.
+// When ctx is cancelled the server is shut down gracefully within the configured
+// ShutdownTimeout. Listen errors (e.g. address already in use) are returned to
+// the caller.
+func (s *Server) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
 	go func() {
-		if err := s.Start(); err != nil {
+		errCh <- s.Start()
+	}()
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-s.Ready():
+			notifyReady()
+		case <-stopped:
+		}
+	}()
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	go s.watchConfig(watchCtx)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
 			s.logger.Error("server failed to start",
 				slog.String("error", err.Error()),
 			)
-			os.Exit(1)
 		}
-	}()
-
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+		return err
+	case <-ctx.Done():
+	}
 
 	s.logger.Info("shutting down server")
 
-	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	shutdownCtx, cancel := s.shutdownContext(s.Config().ShutdownDelay)
 	defer cancel()
 
-	if err := s.server.Shutdown(ctx); err != nil {
+	if err := s.Shutdown(shutdownCtx); err != nil {
 		s.logger.Error("failed to shutdown server gracefully",
 			slog.String("error", err.Error()),
 		)
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 
+	if err := <-errCh; err != nil {
+		return err
+	}
+
 	s.logger.Info("server stopped")
 	return nil
 }
 
-// Shutdown gracefully shuts down the server
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+// shutdownContext returns a context bounded by delay plus the configured
+// ShutdownTimeout. A zero timeout means shutdown waits for active
+// connections indefinitely.
+func (s *Server) shutdownContext(delay time.Duration) (context.Context, context.CancelFunc) {
+	cfg := s.Config()
+	if cfg.ShutdownTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), delay+cfg.ShutdownTimeout)
+}
+
+// Health returns the server's health registry
+func (s *Server) Health() *Health {
+	return s.health
 }
 
-// Address returns the server address
+// Address returns the bound listener address once the server has started,
+// or the configured address otherwise. Unix socket addresses are prefixed
+// with "unix:".
 func (s *Server) Address() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return listenerAddress(s.listener)
+	}
 	return s.server.Addr
 }
 
@@ -126,53 +443,106 @@ func (sm *ServerManager) AddServer(server *Server) {
 	sm.servers = append(sm.servers, server)
 }
 
+// AddMetricsServer adds a dedicated server exposing Prometheus metrics on
+// MetricsPath. It is placed first so it starts before and stops after every
+// other managed server.
+func (sm *ServerManager) AddMetricsServer(name string, cfg Config) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, MetricsHandler())
+
+	server := NewServerWithConfig(name, mux, cfg, sm.logger)
+	sm.servers = append([]*Server{server}, sm.servers...)
+	return server
+}
+
 // StartAll starts all managed servers with graceful shutdown handling
+// on SIGINT/SIGTERM. On SIGUSR2, or SIGHUP when no server has a config
+// source to reload, the listeners are handed to a new instance of the
+// executable (see Upgrade) and the servers drain once it is ready.
 func (sm *ServerManager) StartAll() error {
-	// Start all servers in separate goroutines
+	reloadable := slices.ContainsFunc(sm.servers, func(s *Server) bool { return s.source != nil })
+	return runWithSignals(sm.logger, sm.Run, sm.Upgrade, reloadable)
+}
+
+// Run starts all managed servers in the order they were added, waiting for
+// each one to be ready before starting the next. It blocks until ctx is
+// cancelled or any server fails. Readiness of every started server then
+// fails at once and the longest ShutdownDelay is waited a single time,
+// before the servers are drained in reverse order, each bounded by its own
+// ShutdownTimeout. All errors are returned joined.
+func (sm *ServerManager) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	startErrs := make(chan error, len(sm.servers))
+	started := make([]*Server, 0, len(sm.servers))
+
 	for _, server := range sm.servers {
+		if ctx.Err() != nil {
+			break
+		}
+
 		go func(srv *Server) {
-			if err := srv.Start(); err != nil {
+			err := srv.Start()
+			if err != nil {
 				srv.logger.Error("server failed to start",
 					slog.String("error", err.Error()),
 				)
-				os.Exit(1)
+				cancel()
 			}
+			startErrs <- err
 		}(server)
+		started = append(started, server)
+		go server.watchConfig(ctx)
+
+		// Wait for the server to bind before starting the next one
+		select {
+		case <-server.Ready():
+		case <-ctx.Done():
+		}
+	}
 
-		// Small delay to ensure servers start in order
-		time.Sleep(100 * time.Millisecond)
+	if ctx.Err() == nil {
+		notifyReady()
 	}
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	<-ctx.Done()
 
 	sm.logger.Info("shutting down all servers")
 
-	// Shutdown all servers concurrently
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// The delays would add up if each server waited for its own
+	var delay time.Duration
+	for _, srv := range started {
+		srv.health.SetShuttingDown()
+		delay = max(delay, srv.Config().ShutdownDelay)
+	}
+	waitShutdownDelay(context.Background(), sm.logger, delay)
 
-	done := make(chan error, len(sm.servers))
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		srv := started[i]
 
-	for _, server := range sm.servers {
-		go func(srv *Server) {
-			done <- srv.Shutdown(shutdownCtx)
-		}(server)
-	}
+		shutdownCtx, cancelShutdown := srv.shutdownContext(0)
+		err := srv.drain(shutdownCtx)
+		cancelShutdown()
 
-	// Wait for all servers to shutdown
-	var lastErr error
-	for i := 0; i < len(sm.servers); i++ {
-		if err := <-done; err != nil {
-			sm.logger.Error("server shutdown error",
+		if err != nil {
+			srv.logger.Error("server shutdown error",
 				slog.String("error", err.Error()),
 			)
-			lastErr = err
+			errs = append(errs, fmt.Errorf("shutting down server [%s]: %w", srv.name, err))
+			continue
+		}
+		srv.logger.Info("server stopped")
+	}
+
+	// Collect the result of every Start call
+	for range started {
+		if err := <-startErrs; err != nil {
+			errs = append(errs, err)
 		}
 	}
 
 	sm.logger.Info("all servers stopped")
-	return lastErr
+	return errors.Join(errs...)
 }
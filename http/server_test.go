@@ -0,0 +1,216 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testConfig(address string) Config {
+	return Config{
+		Address:           address,
+		ReadHeaderTimeout: time.Second,
+		ReadTimeout:       time.Second,
+		WriteTimeout:      time.Second,
+		IdleTimeout:       time.Second,
+		ShutdownTimeout:   time.Second,
+	}
+}
+
+func TestServerRun_ContextCancel(t *testing.T) {
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), testConfig("127.0.0.1:0"), testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+
+	waitReady(t, srv)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+func TestServerRun_ListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), testConfig(ln.Addr().String()), testLogger())
+
+	select {
+	case err := <-runAsync(srv.Run, context.Background()):
+		if err == nil {
+			t.Fatal("expected error for address already in use")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return listen error")
+	}
+}
+
+func TestServerManagerRun_ContextCancel(t *testing.T) {
+	sm := NewServerManager(testLogger())
+	sm.AddServer(NewServerWithConfig("api", http.NotFoundHandler(), testConfig("127.0.0.1:0"), testLogger()))
+	sm.AddServer(NewServerWithConfig("admin", http.NotFoundHandler(), testConfig("127.0.0.1:0"), testLogger()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(sm.Run, ctx)
+
+	waitReady(t, sm.servers...)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+func TestServerManagerRun_ListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	sm := NewServerManager(testLogger())
+	sm.AddServer(NewServerWithConfig("api", http.NotFoundHandler(), testConfig("127.0.0.1:0"), testLogger()))
+	sm.AddServer(NewServerWithConfig("admin", http.NotFoundHandler(), testConfig(ln.Addr().String()), testLogger()))
+
+	select {
+	case err := <-runAsync(sm.Run, context.Background()):
+		if err == nil {
+			t.Fatal("expected error for address already in use")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return listen error")
+	}
+}
+
+func runAsync(run func(context.Context) error, ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx)
+	}()
+	return done
+}
+
+func waitReady(t *testing.T, servers ...*Server) {
+	t.Helper()
+	for _, srv := range servers {
+		select {
+		case <-srv.Ready():
+		case <-time.After(2 * time.Second):
+			t.Fatalf("server %s did not become ready", srv.Name())
+		}
+	}
+}
+
+func TestServerReady(t *testing.T) {
+	srv := NewServerWithConfig("test", http.NotFoundHandler(), testConfig("127.0.0.1:0"), testLogger())
+
+	select {
+	case <-srv.Ready():
+		t.Fatal("server reported ready before start")
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	select {
+	case <-srv.Ready():
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+}
+
+func TestServerManagerRun_ShutdownOrder(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		stopped []string
+	)
+	logger := slog.New(&recordHandler{fn: func(r slog.Record, attrs map[string]string) {
+		if r.Message == "server stopped" {
+			mu.Lock()
+			stopped = append(stopped, attrs["server"])
+			mu.Unlock()
+		}
+	}})
+
+	sm := NewServerManager(logger)
+	for _, name := range []string{"admin", "internal", "public"} {
+		sm.AddServer(NewServerWithConfig(name, http.NotFoundHandler(), testConfig("127.0.0.1:0"), logger))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(sm.Run, ctx)
+
+	waitReady(t, sm.servers...)
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	want := []string{"public", "internal", "admin"}
+	if len(stopped) != len(want) {
+		t.Fatalf("expected %d stopped servers, got %v", len(want), stopped)
+	}
+	for i := range want {
+		if stopped[i] != want[i] {
+			t.Fatalf("expected shutdown order %v, got %v", want, stopped)
+		}
+	}
+}
+
+// recordHandler is a slog.Handler that passes each record and its
+// string attributes (including those added via With) to fn.
+type recordHandler struct {
+	fn    func(slog.Record, map[string]string)
+	attrs []slog.Attr
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]string)
+	for _, a := range h.attrs {
+		attrs[a.Key] = a.Value.String()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+	h.fn(r, attrs)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordHandler{fn: h.fn, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
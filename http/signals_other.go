@@ -0,0 +1,12 @@
+//go:build !unix
+
+package http
+
+import "os"
+
+// upgradeSignals is empty since SIGUSR2 and SIGHUP are not available on this
+// platform.
+var upgradeSignals []os.Signal
+
+// reloadSignals is empty since SIGHUP is not available on this platform.
+var reloadSignals []os.Signal
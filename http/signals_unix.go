@@ -0,0 +1,16 @@
+//go:build unix
+
+package http
+
+import (
+	"os"
+	"syscall"
+)
+
+// upgradeSignals trigger a zero-downtime upgrade in StartWithGracefulShutdown
+// and StartAll. SIGHUP only does when no server reloads its config on it.
+var upgradeSignals = []os.Signal{syscall.SIGUSR2, syscall.SIGHUP}
+
+// reloadSignals make Run reload the config of servers created with
+// NewServerFromSource.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
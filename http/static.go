@@ -0,0 +1,211 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticConfig configures a static file handler.
+type StaticConfig struct {
+	// Index is served for directories (index.html by default).
+	Index string
+	// SPA serves Index for paths that don't match a file and have no
+	// extension, so client-side routes load the app.
+	SPA bool
+	// CacheControl rules are matched in order against the path of the
+	// served file; the first match sets Cache-Control. Files matching no
+	// rule get DefaultCacheControl, if set.
+	CacheControl        []CacheRule
+	DefaultCacheControl string
+}
+
+// CacheRule sets Cache-Control for files whose path, without the leading
+// slash, matches Pattern. Pattern uses path.Match syntax, so "*" does not
+// cross directories ("assets/*" matches "assets/app.js" only).
+type CacheRule struct {
+	Pattern string
+	Value   string
+}
+
+// staticEncodings are the pre-compressed variants looked up for each file,
+// in order of preference.
+var staticEncodings = []struct {
+	name string
+	ext  string
+}{
+	{name: "br", ext: ".br"},
+	{name: "gzip", ext: ".gz"},
+}
+
+type staticHandler struct {
+	fsys fs.FS
+	cfg  StaticConfig
+
+	mu    sync.Mutex
+	etags map[string]staticETag
+}
+
+type staticETag struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// Static serves files from fsys, such as an embed.FS (use fs.Sub to serve a
+// subdirectory). Responses carry an ETag computed from the file contents and
+// Last-Modified when the file system provides modification times, and
+// conditional and range requests are honoured. When a client accepts it, a
+// pre-compressed "<file>.br" or "<file>.gz" next to the file is served
+// instead with the matching Content-Encoding.
+func Static(fsys fs.FS, cfg StaticConfig) http.Handler {
+	if cfg.Index == "" {
+		cfg.Index = "index.html"
+	}
+	return &staticHandler{fsys: fsys, cfg: cfg, etags: make(map[string]staticETag)}
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := h.resolve(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := h.serveFile(w, r, name); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// resolve maps a request path to the name of the file to serve.
+func (h *staticHandler) resolve(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, h.cfg.Index)
+		info, err = fs.Stat(h.fsys, name)
+	}
+	if err == nil && !info.IsDir() {
+		return name, true
+	}
+
+	if h.cfg.SPA && path.Ext(name) == "" {
+		if info, err := fs.Stat(h.fsys, h.cfg.Index); err == nil && !info.IsDir() {
+			return h.cfg.Index, true
+		}
+	}
+	return "", false
+}
+
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) error {
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+
+	served := name
+	for _, enc := range staticEncodings {
+		if !acceptsEncoding(r, enc.name) {
+			continue
+		}
+		if info, err := fs.Stat(h.fsys, name+enc.ext); err == nil && !info.IsDir() {
+			served = name + enc.ext
+			header.Set("Content-Encoding", enc.name)
+			break
+		}
+	}
+
+	f, err := h.fsys.Open(served)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := h.etag(served, info, content)
+	if err != nil {
+		return err
+	}
+	header.Set("ETag", etag)
+
+	// Typed after the original file, not its compressed variant
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+	if cc := h.cacheControl(name); cc != "" {
+		header.Set("Cache-Control", cc)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+	return nil
+}
+
+// etag returns the strong ETag of the file, hashing its contents the first
+// time it is served or after it changes.
+func (h *staticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	h.mu.Lock()
+	cached, ok := h.etags[name]
+	h.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.etag, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := strconv.Quote(hex.EncodeToString(hash.Sum(nil)[:16]))
+	h.mu.Lock()
+	h.etags[name] = staticETag{modTime: info.ModTime(), size: info.Size(), etag: etag}
+	h.mu.Unlock()
+	return etag, nil
+}
+
+func (h *staticHandler) cacheControl(name string) string {
+	for _, rule := range h.cfg.CacheControl {
+		if ok, _ := path.Match(rule.Pattern, name); ok {
+			return rule.Value
+		}
+	}
+	return h.cfg.DefaultCacheControl
+}
+
+// acceptsEncoding reports whether the request's Accept-Encoding allows
+// encoding.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	return encodingQuality(r.Header.Get("Accept-Encoding"), encoding) > 0
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func testStaticFS() fstest.MapFS {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return fstest.MapFS{
+		"index.html":          {Data: []byte("<html>app</html>"), ModTime: modTime},
+		"assets/app.js":       {Data: []byte("console.log('app')"), ModTime: modTime},
+		"assets/app.js.br":    {Data: []byte("br-bytes"), ModTime: modTime},
+		"assets/app.js.gz":    {Data: []byte("gz-bytes"), ModTime: modTime},
+		"docs/index.html":     {Data: []byte("<html>docs</html>"), ModTime: modTime},
+		"assets/style.css":    {Data: []byte("body{}"), ModTime: modTime},
+		"assets/style.css.gz": {Data: []byte("gz-css"), ModTime: modTime},
+	}
+}
+
+func TestStatic(t *testing.T) {
+	h := Static(testStaticFS(), StaticConfig{
+		SPA: true,
+		CacheControl: []CacheRule{
+			{Pattern: "assets/*", Value: "public, max-age=31536000, immutable"},
+			{Pattern: "*.html", Value: "no-cache"},
+		},
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantStatus     int
+		wantBody       string
+		wantEncoding   string
+		wantType       string
+		wantCache      string
+	}{
+		{name: "index", path: "/", wantStatus: 200, wantBody: "<html>app</html>", wantType: "text/html; charset=utf-8", wantCache: "no-cache"},
+		{name: "directory index", path: "/docs/", wantStatus: 200, wantBody: "<html>docs</html>", wantType: "text/html; charset=utf-8"},
+		{name: "asset", path: "/assets/app.js", wantStatus: 200, wantBody: "console.log('app')", wantType: "text/javascript; charset=utf-8", wantCache: "public, max-age=31536000, immutable"},
+		{name: "brotli preferred", path: "/assets/app.js", acceptEncoding: "gzip, br", wantStatus: 200, wantBody: "br-bytes", wantEncoding: "br", wantType: "text/javascript; charset=utf-8", wantCache: "public, max-age=31536000, immutable"},
+		{name: "gzip", path: "/assets/style.css", acceptEncoding: "gzip, br", wantStatus: 200, wantBody: "gz-css", wantEncoding: "gzip", wantType: "text/css; charset=utf-8", wantCache: "public, max-age=31536000, immutable"},
+		{name: "refused encoding", path: "/assets/app.js", acceptEncoding: "br;q=0, gzip;q=0", wantStatus: 200, wantBody: "console.log('app')", wantCache: "public, max-age=31536000, immutable"},
+		{name: "spa fallback", path: "/users/42", wantStatus: 200, wantBody: "<html>app</html>", wantCache: "no-cache"},
+		{name: "missing asset", path: "/assets/missing.js", wantStatus: 404},
+		{name: "traversal", path: "/../../etc/passwd.conf", wantStatus: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.URL.Path = tt.path
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("expected content encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if tt.wantType != "" && rec.Header().Get("Content-Type") != tt.wantType {
+				t.Errorf("expected content type %q, got %q", tt.wantType, rec.Header().Get("Content-Type"))
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("expected cache control %q, got %q", tt.wantCache, got)
+			}
+		})
+	}
+}
+
+func TestStatic_Conditional(t *testing.T) {
+	h := Static(testStaticFS(), StaticConfig{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag")
+	}
+	if rec.Header().Get("Last-Modified") != "Tue, 02 Jan 2024 03:04:05 GMT" {
+		t.Errorf("expected Last-Modified, got %q", rec.Header().Get("Last-Modified"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("ETag") == etag {
+		t.Error("expected the compressed variant to have its own ETag")
+	}
+}
+
+func TestStatic_NoSPA(t *testing.T) {
+	h := Static(testStaticFS(), StaticConfig{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without SPA fallback, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate and key for 127.0.0.1 to dir
+// and returns their paths.
+func writeTestCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, commonName+".crt")
+	keyFile = filepath.Join(dir, commonName+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
+
+func TestConfigTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "server")
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			cfg:  Config{TLSCertFile: certFile, TLSKeyFile: keyFile},
+		},
+		{
+			name: "tls 1.3 with cipher suites",
+			cfg: Config{
+				TLSCertFile:     certFile,
+				TLSKeyFile:      keyFile,
+				TLSMinVersion:   "1.3",
+				TLSCipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+			},
+		},
+		{
+			name:    "missing key",
+			cfg:     Config{TLSCertFile: certFile},
+			wantErr: true,
+		},
+		{
+			name:    "invalid min version",
+			cfg:     Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: "1.0"},
+			wantErr: true,
+		},
+		{
+			name:    "insecure cipher suite",
+			cfg:     Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSCipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid client ca",
+			cfg:     Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: keyFile},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.TLSConfig()
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "server")
+
+	r, err := newCertReloader(certFile, keyFile, 0, testLogger())
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+
+	first, _ := r.GetCertificate(nil)
+
+	// Rotate the files with a new certificate and a newer mod time
+	newCert, newKey := writeTestCert(t, t.TempDir(), "server")
+	for src, dst := range map[string]string{newCert: certFile, newKey: keyFile} {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if err := os.WriteFile(dst, data, 0o600); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		future := time.Now().Add(time.Minute)
+		if err := os.Chtimes(dst, future, future); err != nil {
+			t.Fatalf("failed to touch: %v", err)
+		}
+	}
+
+	second, _ := r.GetCertificate(nil)
+	if string(first.Certificate[0]) == string(second.Certificate[0]) {
+		t.Fatal("expected certificate to be reloaded after rotation")
+	}
+}
+
+func TestCertReloader_LogsFailedReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "server")
+
+	var failures []string
+	logger := slog.New(&recordHandler{fn: func(r slog.Record, attrs map[string]string) {
+		failures = append(failures, attrs["error"])
+	}})
+
+	r, err := newCertReloader(certFile, keyFile, time.Hour, logger)
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+	first, _ := r.GetCertificate(nil)
+
+	// Break the certificate and force the next call to check the files
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, future, future); err != nil {
+		t.Fatalf("failed to touch: %v", err)
+	}
+	r.lastCheck = time.Time{}
+
+	for range 3 {
+		got, err := r.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != first {
+			t.Fatal("expected the previous certificate to keep being served")
+		}
+	}
+
+	if len(failures) != 1 {
+		t.Fatalf("expected 1 logged failure within the reload interval, got %d", len(failures))
+	}
+}
+
+func TestServerRun_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "server")
+	clientCert, clientKey := writeTestCert(t, dir, "client")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := testConfig(addr)
+	cfg.TLSCertFile = certFile
+	cfg.TLSKeyFile = keyFile
+	cfg.TLSClientCAFile = clientCert
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := NewServerWithConfig("test", handler, cfg, testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(srv.Run, ctx)
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-srv.Ready()
+
+	rootPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("failed to read cert: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(rootPEM)
+
+	keyPair, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("failed to load client cert: %v", err)
+	}
+
+	withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{keyPair},
+	}}}
+	resp, err := withCert.Get("https://" + addr)
+	if err != nil {
+		t.Fatalf("request with client cert failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+
+	withoutCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if resp, err := withoutCert.Get("https://" + addr); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected request without client cert to fail")
+	}
+}
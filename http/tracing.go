@@ -0,0 +1,124 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/guilhermebr/gox/http"
+
+// propagator reads and writes W3C traceparent, tracestate and baggage
+// headers.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// tracerProvider returns tp, or the global provider when tp is nil. The
+// global provider is looked up on each call so it can be installed after the
+// server is created.
+func tracerProvider(tp trace.TracerProvider) trace.TracerProvider {
+	if tp != nil {
+		return tp
+	}
+	return otel.GetTracerProvider()
+}
+
+// Tracing starts a server span for each request, continuing the trace from
+// the incoming traceparent header. The span is named after the matched
+// ServeMux pattern and stored in the request context, so spans created by
+// handlers (and the postgres and supabase packages) become its children.
+// A nil tp uses the global provider, which records nothing until an SDK is
+// installed with otel.SetTracerProvider.
+func Tracing(server string, tp trace.TracerProvider) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracerProvider(tp).Tracer(tracerName).Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					attribute.String("server.name", server),
+				),
+			)
+			defer span.End()
+
+			if id := RequestIDFromContext(ctx); id != "" {
+				span.SetAttributes(attribute.String("http.request.id", id))
+			}
+
+			rw := wrapResponseWriter(w)
+			r = r.WithContext(ctx)
+			next.ServeHTTP(rw, r)
+
+			if r.Pattern != "" {
+				span.SetName(r.Method + " " + patternPath(r.Pattern))
+				span.SetAttributes(semconv.HTTPRoute(patternPath(r.Pattern)))
+			}
+			status := rw.Status()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// patternPath strips the method and host from a ServeMux pattern such as
+// "GET example.com/users/{id}".
+func patternPath(pattern string) string {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '/' {
+			return pattern[i:]
+		}
+	}
+	return pattern
+}
+
+// TracingTransport wraps base (http.DefaultTransport when nil) so outgoing
+// requests get a client span and carry the traceparent header of the
+// request context.
+func TracingTransport(base http.RoundTripper, tp trace.TracerProvider) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracingTransport{base: base, tp: tp}
+}
+
+type tracingTransport struct {
+	base http.RoundTripper
+	tp   trace.TracerProvider
+}
+
+func (t *tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := tracerProvider(t.tp).Tracer(tracerName).Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLFull(r.URL.Redacted()),
+			semconv.ServerAddress(r.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	// RoundTrippers must not modify the caller's request
+	r = r.Clone(ctx)
+	propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
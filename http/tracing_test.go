@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newInMemoryTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func TestTracing(t *testing.T) {
+	tp, exporter := newInMemoryTracerProvider()
+
+	var handlerSpan trace.SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	h := Chain(mux, RequestID(), Tracing("api", tp))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "GET /users/{id}" {
+		t.Errorf("expected span name from route, got %q", span.Name)
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("expected server span, got %v", span.SpanKind)
+	}
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected trace ID from traceparent, got %s", got)
+	}
+	if got := span.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("expected remote parent span, got %s", got)
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Error("expected the span to be in the handler's context")
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("expected error status for 500, got %v", span.Status.Code)
+	}
+
+	attrs := make(map[string]string)
+	for _, kv := range span.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	want := map[string]string{
+		string(semconv.HTTPRouteKey):              "/users/{id}",
+		string(semconv.HTTPResponseStatusCodeKey): "500",
+		"server.name": "api",
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("expected attribute %s=%s, got %q", k, v, attrs[k])
+		}
+	}
+	if attrs["http.request.id"] == "" {
+		t.Error("expected request ID attribute")
+	}
+}
+
+func TestTracingTransport(t *testing.T) {
+	tp, exporter := newInMemoryTracerProvider()
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	ctx, parent := tp.Tracer("test").Start(t.Context(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+
+	client := &http.Client{Transport: TracingTransport(nil, tp)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if req.Header.Get("traceparent") != "" {
+		t.Error("expected the caller's request not to be modified")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	clientSpan := spans[0]
+	if clientSpan.SpanKind != trace.SpanKindClient {
+		t.Errorf("expected client span, got %v", clientSpan.SpanKind)
+	}
+	if clientSpan.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected client span to be a child of the context span")
+	}
+
+	want := "00-" + clientSpan.SpanContext.TraceID().String() + "-" + clientSpan.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("expected traceparent %s, got %s", want, traceparent)
+	}
+}
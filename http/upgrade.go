@@ -0,0 +1,347 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Environment variables used to hand listeners to an upgraded process.
+// Listener file descriptors start at 3 in the order of the JSON encoded
+// upgradeSocket list; the readiness pipe follows them.
+const (
+	upgradeSocketsEnv = "GOX_UPGRADE_SOCKETS"
+	upgradeReadyFDEnv = "GOX_UPGRADE_READY_FD"
+)
+
+// upgradeSocket describes a socket handed to an upgraded process. Address
+// is the configured address of its server, not the bound one, so servers
+// listening on port 0 find their socket again.
+type upgradeSocket struct {
+	Address string `json:"address"`
+	HTTP3   bool   `json:"http3,omitempty"`
+}
+
+// upgradeTimeout bounds how long the old process waits for the new one to
+// become ready when the upgrade is triggered by a signal.
+const upgradeTimeout = time.Minute
+
+// inherited holds the listeners passed by a parent process during an
+// upgrade, queued per configured address. Servers configured with the same
+// address (e.g. several on "127.0.0.1:0") get them in the order they were
+// handed over, which is the order the servers start in.
+var inherited struct {
+	once        sync.Once
+	mu          sync.Mutex
+	listeners   map[string][]net.Listener
+	packetConns map[string][]net.PacketConn
+	ready       *os.File
+	notified    bool
+}
+
+func loadInherited() {
+	inherited.once.Do(func() {
+		encoded := os.Getenv(upgradeSocketsEnv)
+		readyFD := os.Getenv(upgradeReadyFDEnv)
+		os.Unsetenv(upgradeSocketsEnv)
+		os.Unsetenv(upgradeReadyFDEnv)
+
+		inherited.listeners = make(map[string][]net.Listener)
+		inherited.packetConns = make(map[string][]net.PacketConn)
+
+		var sockets []upgradeSocket
+		if encoded != "" {
+			// A malformed list leaves the descriptors unused; servers bind anew
+			_ = json.Unmarshal([]byte(encoded), &sockets)
+		}
+		for i, sock := range sockets {
+			f := os.NewFile(uintptr(listenFDsStart+i), "upgrade:"+sock.Address)
+			if sock.HTTP3 {
+				if pc, err := net.FilePacketConn(f); err == nil {
+					inherited.packetConns[sock.Address] = append(inherited.packetConns[sock.Address], pc)
+				}
+			} else if ln, err := net.FileListener(f); err == nil {
+				inherited.listeners[sock.Address] = append(inherited.listeners[sock.Address], ln)
+			}
+			f.Close()
+		}
+
+		if fd, err := strconv.Atoi(readyFD); err == nil {
+			inherited.ready = os.NewFile(uintptr(fd), "upgrade:ready")
+		}
+	})
+}
+
+// inheritedListener returns the listener the parent process bound for addr,
+// if this process was started by an upgrade.
+func inheritedListener(addr string) (net.Listener, bool) {
+	loadInherited()
+
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+
+	return shift(inherited.listeners, addr)
+}
+
+// inheritedPacketConn returns the HTTP/3 UDP socket the parent process bound
+// for the server configured with addr, if this process was started by an
+// upgrade.
+func inheritedPacketConn(addr string) (net.PacketConn, bool) {
+	loadInherited()
+
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+
+	return shift(inherited.packetConns, addr)
+}
+
+// shift removes and returns the first value queued for key.
+func shift[T any](queues map[string][]T, key string) (T, bool) {
+	queue := queues[key]
+	if len(queue) == 0 {
+		var zero T
+		return zero, false
+	}
+	if len(queue) == 1 {
+		delete(queues, key)
+	} else {
+		queues[key] = queue[1:]
+	}
+	return queue[0], true
+}
+
+// notifyReady tells systemd (with Type=notify) and, during an upgrade, the
+// parent process that this process is serving, so the parent can drain and
+// exit.
+func notifyReady() {
+	loadInherited()
+
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+
+	if inherited.notified {
+		return
+	}
+	inherited.notified = true
+
+	_ = sdNotify("READY=1")
+
+	if inherited.ready != nil {
+		_, _ = inherited.ready.Write([]byte{1})
+		inherited.ready.Close()
+	}
+}
+
+// Upgrade starts a new instance of the running executable with the same
+// arguments, hands it the server's listener and waits until it is ready.
+// The caller is expected to shut the server down afterwards.
+func (s *Server) Upgrade(ctx context.Context) error {
+	return upgrade(ctx, s.logger, []*Server{s})
+}
+
+// Upgrade starts a new instance of the running executable with the same
+// arguments, hands it the listeners of all managed servers and waits until
+// it is ready. The caller is expected to shut the servers down afterwards.
+func (sm *ServerManager) Upgrade(ctx context.Context) error {
+	return upgrade(ctx, sm.logger, sm.servers)
+}
+
+func upgrade(ctx context.Context, logger *slog.Logger, servers []*Server) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolving executable: %w", err)
+	}
+
+	var (
+		sockets []upgradeSocket
+		files   []*os.File
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for _, srv := range servers {
+		srv.mu.Lock()
+		ln := srv.listener
+		srv.mu.Unlock()
+
+		if ln == nil {
+			return fmt.Errorf("server [%s] is not listening", srv.name)
+		}
+
+		filer, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("server [%s] listener %T cannot be passed to another process", srv.name, ln)
+		}
+
+		f, err := filer.File()
+		if err != nil {
+			return fmt.Errorf("duplicating listener of server [%s]: %w", srv.name, err)
+		}
+
+		sockets = append(sockets, upgradeSocket{Address: srv.server.Addr})
+		files = append(files, f)
+
+		srv.mu.Lock()
+		pc := srv.http3Conn
+		srv.mu.Unlock()
+
+		if udp, ok := pc.(*net.UDPConn); ok {
+			f, err := udp.File()
+			if err != nil {
+				return fmt.Errorf("duplicating http3 socket of server [%s]: %w", srv.name, err)
+			}
+
+			sockets = append(sockets, upgradeSocket{Address: srv.server.Addr, HTTP3: true})
+			files = append(files, f)
+		}
+	}
+
+	encoded, err := json.Marshal(sockets)
+	if err != nil {
+		return fmt.Errorf("encoding sockets: %w", err)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("creating readiness pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(os.Environ(),
+		upgradeSocketsEnv+"="+string(encoded),
+		upgradeReadyFDEnv+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return fmt.Errorf("starting new process: %w", err)
+	}
+
+	logger.Info("waiting for upgraded process",
+		slog.Int("pid", cmd.Process.Pid),
+	)
+
+	ready := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		if _, err := readyR.Read(b); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("new process exited before becoming ready")
+			}
+			ready <- err
+			return
+		}
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("upgrade failed: %w", err)
+	}
+
+	// The new process owns the sockets now; don't remove unix socket files
+	// when our listeners close.
+	for _, srv := range servers {
+		srv.mu.Lock()
+		if ul, ok := srv.listener.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+		srv.mu.Unlock()
+	}
+
+	// Under systemd, the new process becomes the main process of the unit
+	if err := sdNotify("MAINPID=" + strconv.Itoa(cmd.Process.Pid)); err != nil {
+		logger.Warn("failed to notify systemd of the new main pid",
+			slog.String("error", err.Error()),
+		)
+	}
+
+	// The new process keeps running after this one exits
+	_ = cmd.Process.Release()
+
+	logger.Info("upgraded process is ready",
+		slog.Int("pid", cmd.Process.Pid),
+	)
+	return nil
+}
+
+// runWithSignals runs run until SIGINT/SIGTERM. On an upgrade signal it hands
+// the listeners to a new process via upgradeFn and, once that process is ready,
+// stops run so the remaining requests drain. When reloadable, the reload
+// signals reload the config instead of upgrading.
+func runWithSignals(logger *slog.Logger, run, upgradeFn func(context.Context) error, reloadable bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	signals := upgradeSignals
+	if reloadable {
+		signals = slices.DeleteFunc(slices.Clone(signals), func(sig os.Signal) bool {
+			return slices.Contains(reloadSignals, sig)
+		})
+	}
+
+	if len(signals) > 0 {
+		upgradeCh := make(chan os.Signal, 1)
+		signal.Notify(upgradeCh, signals...)
+		defer signal.Stop(upgradeCh)
+
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-upgradeCh:
+				}
+
+				logger.Info("upgrade requested")
+
+				upgradeCtx, cancelUpgrade := context.WithTimeout(ctx, upgradeTimeout)
+				err := upgradeFn(upgradeCtx)
+				cancelUpgrade()
+
+				if err != nil {
+					logger.Error("upgrade failed, continuing to serve",
+						slog.String("error", err.Error()),
+					)
+					continue
+				}
+
+				cancel()
+				return
+			}
+		}()
+	}
+
+	return run(ctx)
+}
@@ -0,0 +1,152 @@
+//go:build unix
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+const upgradeChildEnv = "GOX_TEST_UPGRADE_CHILD"
+
+func TestServerUpgrade(t *testing.T) {
+	if os.Getenv(upgradeChildEnv) == "1" {
+		runUpgradeChild()
+		return
+	}
+
+	parent := NewServerWithConfig("test", textHandler("parent"), testConfig("127.0.0.1:0"), testLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(parent.Run, ctx)
+	<-parent.Ready()
+	addr := parent.Address()
+
+	if got := get(t, addr); got != "parent" {
+		t.Fatalf("expected response from parent, got %q", got)
+	}
+
+	// Re-exec only this test in the new process
+	t.Setenv(upgradeChildEnv, "1")
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestServerUpgrade$"}
+	defer func() { os.Args = args }()
+
+	upgradeCtx, cancelUpgrade := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelUpgrade()
+	if err := parent.Upgrade(upgradeCtx); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("parent shutdown failed: %v", err)
+	}
+
+	if got := get(t, addr); got != "child" {
+		t.Fatalf("expected response from child after upgrade, got %q", got)
+	}
+}
+
+func TestServerManagerUpgrade(t *testing.T) {
+	if os.Getenv(upgradeChildEnv) == "manager" {
+		runManagerUpgradeChild()
+		return
+	}
+
+	// Both servers have the same configured address, so the handoff must
+	// not mix up their listeners
+	first := NewServerWithConfig("first", textHandler("parent first"), testConfig("127.0.0.1:0"), testLogger())
+	second := NewServerWithConfig("second", textHandler("parent second"), testConfig("127.0.0.1:0"), testLogger())
+	sm := NewServerManager(testLogger())
+	sm.AddServer(first)
+	sm.AddServer(second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(sm.Run, ctx)
+	<-first.Ready()
+	<-second.Ready()
+	firstAddr, secondAddr := first.Address(), second.Address()
+
+	t.Setenv(upgradeChildEnv, "manager")
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestServerManagerUpgrade$"}
+	defer func() { os.Args = args }()
+
+	upgradeCtx, cancelUpgrade := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelUpgrade()
+	if err := sm.Upgrade(upgradeCtx); err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("parent shutdown failed: %v", err)
+	}
+
+	if got := get(t, firstAddr); got != "child first" {
+		t.Errorf("expected response from first child server, got %q", got)
+	}
+	if got := get(t, secondAddr); got != "child second" {
+		t.Errorf("expected response from second child server, got %q", got)
+	}
+}
+
+// runUpgradeChild serves on the inherited listener for a short while and
+// exits without running the rest of the test binary.
+func runUpgradeChild() {
+	srv := NewServerWithConfig("test", textHandler("child"), testConfig("127.0.0.1:0"), testLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	code := 0
+	if err := srv.Run(ctx); err != nil {
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// runManagerUpgradeChild serves both servers of TestServerManagerUpgrade on
+// the inherited listeners for a short while.
+func runManagerUpgradeChild() {
+	sm := NewServerManager(testLogger())
+	sm.AddServer(NewServerWithConfig("first", textHandler("child first"), testConfig("127.0.0.1:0"), testLogger()))
+	sm.AddServer(NewServerWithConfig("second", textHandler("child second"), testConfig("127.0.0.1:0"), testLogger()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	code := 0
+	if err := sm.Run(ctx); err != nil {
+		code = 1
+	}
+	os.Exit(code)
+}
+
+func textHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+func get(t *testing.T, addr string) string {
+	t.Helper()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return string(body)
+}
@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenClaims are the claims of a token carrying custom claims of type T,
+// such as roles, a tenant ID or scopes. T is encoded as a JSON object
+// whose members sit next to the registered claims; registered claims take
+// precedence when names collide.
+type TokenClaims[T any] struct {
+	Custom T
+	jwt.RegisteredClaims
+	// IssuedAtNanos is the "iat_ns" claim: the nanoseconds past "iat" the
+	// token was issued at, which has second precision. Revocation cutoffs
+	// are compared against both.
+	IssuedAtNanos int64
+}
+
+// issuedAt returns when the token was issued, or the zero time without an
+// "iat" claim.
+func (c TokenClaims[T]) issuedAt() time.Time {
+	if c.IssuedAt == nil {
+		return time.Time{}
+	}
+	return c.IssuedAt.Add(time.Duration(c.IssuedAtNanos))
+}
+
+func (c TokenClaims[T]) MarshalJSON() ([]byte, error) {
+	custom, err := json.Marshal(c.Custom)
+	if err != nil {
+		return nil, fmt.Errorf("encoding custom claims: %w", err)
+	}
+	if !bytes.HasPrefix(custom, []byte("{")) {
+		return nil, errors.New("custom claims must encode to a JSON object")
+	}
+	registered, err := json.Marshal(c.RegisteredClaims)
+	if err != nil {
+		return nil, fmt.Errorf("encoding registered claims: %w", err)
+	}
+
+	members := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(custom, &members); err != nil {
+		return nil, fmt.Errorf("encoding custom claims: %w", err)
+	}
+	if err := json.Unmarshal(registered, &members); err != nil {
+		return nil, fmt.Errorf("encoding registered claims: %w", err)
+	}
+	if c.IssuedAtNanos != 0 {
+		members["iat_ns"] = json.RawMessage(strconv.FormatInt(c.IssuedAtNanos, 10))
+	}
+	return json.Marshal(members)
+}
+
+func (c *TokenClaims[T]) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &c.Custom); err != nil {
+		return fmt.Errorf("decoding custom claims: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.RegisteredClaims); err != nil {
+		return fmt.Errorf("decoding registered claims: %w", err)
+	}
+
+	var issuedAt struct {
+		Nanos int64 `json:"iat_ns"`
+	}
+	if err := json.Unmarshal(data, &issuedAt); err != nil {
+		return fmt.Errorf("decoding iat_ns claim: %w", err)
+	}
+	if issuedAt.Nanos < 0 || issuedAt.Nanos >= int64(time.Second) {
+		return fmt.Errorf("iat_ns claim out of range [%d]", issuedAt.Nanos)
+	}
+	c.IssuedAtNanos = issuedAt.Nanos
+	return nil
+}
+
+// GenerateToken signs a token for subject carrying custom. The service
+// sets the registered claims.
+func GenerateToken[T any](s Service, subject string, custom T) (string, error) {
+	token, _, err := signToken(s, subject, custom)
+	return token, err
+}
+
+// signToken signs a token for subject carrying custom and returns it with
+// its claims.
+func signToken[T any](s Service, subject string, custom T) (string, *TokenClaims[T], error) {
+	key, ok := s.keys.SigningKey()
+	if !ok {
+		return "", nil, errors.New("no signing key available")
+	}
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &TokenClaims[T]{
+		Custom: custom,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.expiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.issuer,
+			Subject:   subject,
+			ID:        uuid.Must(uuid.NewV4()).String(),
+		},
+		IssuedAtNanos: int64(now.Nanosecond()),
+	}
+	if len(s.validation.Audience) > 0 {
+		claims.Audience = s.validation.Audience
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	signed, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// ValidateToken validates a token signed by s, with the checks of its
+// ValidationOptions, and decodes its custom claims into T.
+func ValidateToken[T any](s Service, tokenString string) (*TokenClaims[T], error) {
+	return ValidateTokenContext[T](context.Background(), s, tokenString)
+}
+
+// ValidateTokenContext is ValidateToken with a context for the revocation
+// check, such as the context of the request being authenticated.
+func ValidateTokenContext[T any](ctx context.Context, s Service, tokenString string) (*TokenClaims[T], error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims[T]{}, s.verificationKey, s.parserOptions()...)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*TokenClaims[T])
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims: %w", ErrInvalidToken)
+	}
+	if err := s.checkClaims(&claims.RegisteredClaims); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRevoked(ctx, claims.ID, claims.Subject, claims.issuedAt()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// userClaims are the custom claims of the tokens of Service.GenerateToken.
+type userClaims struct {
+	UserID      string `json:"user_id"`
+	Email       string `json:"email"`
+	AccountType string `json:"account_type"`
+	Scope       string `json:"scope,omitempty"`
+}
@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type tenantClaims struct {
+	TenantID string   `json:"tenant_id"`
+	Roles    []string `json:"roles"`
+	Scope    string   `json:"scope,omitempty"`
+	// Collides with the registered "sub" claim
+	Subject string `json:"sub,omitempty"`
+}
+
+func TestGenerateToken_CustomClaims(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "1h")
+
+	token, err := GenerateToken(svc, "user-1", tenantClaims{TenantID: "acme", Roles: []string{"admin", "billing"}, Subject: "spoofed"})
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	claims, err := ValidateToken[tenantClaims](svc, token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if claims.Custom.TenantID != "acme" || len(claims.Custom.Roles) != 2 {
+		t.Errorf("expected custom claims, got %+v", claims.Custom)
+	}
+	if claims.Subject != "user-1" || claims.Issuer != "test-issuer" || claims.ID == "" {
+		t.Errorf("expected registered claims set by the service, got %+v", claims.RegisteredClaims)
+	}
+
+	// Custom claims are top-level members of the payload
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	var members map[string]any
+	if err := json.Unmarshal(payload, &members); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if members["tenant_id"] != "acme" || members["sub"] != "user-1" {
+		t.Errorf("expected flat claims with registered sub, got %v", members)
+	}
+}
+
+func TestValidateToken_CustomClaimsCompat(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "1h")
+
+	// Tokens of the fixed API decode as custom claims and vice versa
+	token, _ := svc.GenerateToken("user-1", "test@example.com", "admin")
+	custom, err := ValidateToken[userClaims](svc, token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if custom.Custom.Email != "test@example.com" || custom.Custom.AccountType != "admin" {
+		t.Errorf("unexpected custom claims %+v", custom.Custom)
+	}
+
+	token, _ = GenerateToken(svc, "user-2", userClaims{UserID: "user-2", AccountType: "user"})
+	claims, err := svc.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if claims.UserID != "user-2" || claims.Subject != "user-2" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestValidateToken_CustomClaimsRevoked(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "1h").WithRevocationStore(NewMemoryRevocationStore())
+
+	token, _ := GenerateToken(svc, "user-1", tenantClaims{TenantID: "acme"})
+	if err := svc.RevokeToken(context.Background(), token); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if _, err := ValidateToken[tenantClaims](svc, token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+}
+
+func TestGenerateToken_NonObjectClaims(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "1h")
+	if _, err := GenerateToken(svc, "user-1", []string{"admin"}); err == nil {
+		t.Error("expected error for claims that are not an object")
+	}
+}
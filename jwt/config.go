@@ -10,6 +10,29 @@ type Config struct {
 	SecretKey string `conf:"env:JWT_SECRET_KEY,default:dev-secret-change-me"`
 	Issuer    string `conf:"env:JWT_ISSUER,default:go-app"`
 	Expiry    string `conf:"env:JWT_EXPIRY,default:24h"`
+
+	// RefreshExpiry is the lifetime of refresh tokens; Expiry is the
+	// lifetime of access tokens.
+	RefreshExpiry string `conf:"env:JWT_REFRESH_EXPIRY,default:720h"`
+
+	// Algorithm is HS256, RS256, ES256 or EdDSA. HS256 signs with
+	// SecretKey; the others with the PEM private key, given inline or as a
+	// file. Services that only verify tokens set the public key instead.
+	Algorithm      string `conf:"env:JWT_ALGORITHM,default:HS256"`
+	PrivateKey     string `conf:"env:JWT_PRIVATE_KEY,mask"`
+	PrivateKeyFile string `conf:"env:JWT_PRIVATE_KEY_FILE"`
+	PublicKey      string `conf:"env:JWT_PUBLIC_KEY"`
+	PublicKeyFile  string `conf:"env:JWT_PUBLIC_KEY_FILE"`
+
+	// KeyID is sent as the "kid" header of issued tokens.
+	KeyID string `conf:"env:JWT_KEY_ID"`
+
+	// Audience is set on issued tokens and required on validated ones,
+	// separated by ";". Leeway allows for clock drift between hosts, and
+	// MaxAge rejects tokens issued longer ago whatever their expiry.
+	Audience []string `conf:"env:JWT_AUDIENCE"`
+	Leeway   string   `conf:"env:JWT_LEEWAY,default:0s"`
+	MaxAge   string   `conf:"env:JWT_MAX_AGE"`
 }
 
 func LoadConfig(prefix string) (Config, error) {
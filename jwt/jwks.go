@@ -0,0 +1,179 @@
+package jwt
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+// JWKSPath is where JWKSHandler is conventionally mounted.
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the keyring. HS256 secrets are never
+// published.
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.Keys() {
+		if jwk, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// JWK returns the public key in JWK format. It returns false for HS256
+// keys.
+func (key Key) JWK() (JWK, bool) {
+	jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeBase64(pub.N.Bytes())
+		jwk.E = encodeBase64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdh, err := pub.ECDH()
+		if err != nil {
+			return JWK{}, false
+		}
+		// Uncompressed point: 0x04 || X || Y
+		point := ecdh.Bytes()[1:]
+		size := len(point) / 2
+		jwk.KeyType = "EC"
+		jwk.Curve = pub.Curve.Params().Name
+		jwk.X = encodeBase64(point[:size])
+		jwk.Y = encodeBase64(point[size:])
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encodeBase64(pub)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// Key returns the verification key described by the JWK. The algorithm
+// defaults to the one implied by the key type when "alg" is not set.
+func (j JWK) Key() (Key, error) {
+	key := Key{ID: j.KeyID, Algorithm: j.Algorithm}
+
+	switch j.KeyType {
+	case "RSA":
+		n, errN := decodeBase64(j.N)
+		e, errE := decodeBase64(j.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return Key{}, fmt.Errorf("invalid rsa jwk [%s]", j.KeyID)
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < rsaKeyBits {
+			return Key{}, fmt.Errorf("rsa jwk [%s] has %d bits, at least %d are required", j.KeyID, pub.N.BitLen(), rsaKeyBits)
+		}
+		key.PublicKey = pub
+		if key.Algorithm == "" {
+			key.Algorithm = AlgRS256
+		}
+	case "EC":
+		if j.Curve != "P-256" {
+			return Key{}, fmt.Errorf("unsupported curve [%s] for jwk [%s]", j.Curve, j.KeyID)
+		}
+		x, errX := decodeBase64(j.X)
+		y, errY := decodeBase64(j.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return Key{}, fmt.Errorf("invalid ec jwk [%s]", j.KeyID)
+		}
+		// Rejects points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return Key{}, fmt.Errorf("invalid ec jwk [%s]: %w", j.KeyID, err)
+		}
+		key.PublicKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if key.Algorithm == "" {
+			key.Algorithm = AlgES256
+		}
+	case "OKP":
+		x, err := decodeBase64(j.X)
+		if j.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return Key{}, fmt.Errorf("invalid okp jwk [%s]", j.KeyID)
+		}
+		key.PublicKey = ed25519.PublicKey(x)
+		if key.Algorithm == "" {
+			key.Algorithm = AlgEdDSA
+		}
+	default:
+		return Key{}, fmt.Errorf("unsupported key type [%s] for jwk [%s]", j.KeyType, j.KeyID)
+	}
+
+	return key, key.validate()
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// JWKSHandler serves the public keys of keys as a JWK Set, for mounting at
+// JWKSPath. Keys scheduled to sign later are included so verifiers can
+// cache them ahead of the rotation. Responses carry an ETag so verifiers
+// can revalidate their cached copy.
+func JWKSHandler(keys *Keyring) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := json.Marshal(keys.JWKS())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write(body)
+	})
+}
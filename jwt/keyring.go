@@ -0,0 +1,190 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Keyring holds the keys of a Service: the current signing key and every key
+// still accepted for verification, identified by their ID ("kid"). It is
+// safe for concurrent use.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []Key
+	now  func() time.Time
+}
+
+// NewKeyring returns a keyring holding keys.
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	k := &Keyring{now: time.Now}
+	for _, key := range keys {
+		if err := k.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Add adds key to the keyring. Key IDs must be unique.
+func (k *Keyring) Add(key Key) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, existing := range k.keys {
+		if existing.ID == key.ID {
+			return fmt.Errorf("duplicate key id [%s]", key.ID)
+		}
+	}
+	k.keys = append(k.keys, key)
+	return nil
+}
+
+// Rotate schedules key to become the signing key at activateAt. Signing
+// keys without an expiry that were active before then keep verifying for
+// overlap after it; set overlap to at least the token expiry so tokens
+// issued just before the rotation stay valid. The new key is published and
+// accepted for verification right away.
+func (k *Keyring) Rotate(key Key, activateAt time.Time, overlap time.Duration) error {
+	if key.PrivateKey == nil {
+		return fmt.Errorf("key [%s] cannot sign", key.ID)
+	}
+	key.ActiveFrom = activateAt
+
+	if err := k.Add(key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	expiresAt := activateAt.Add(overlap)
+	for i, existing := range k.keys {
+		if existing.ID == key.ID || existing.PrivateKey == nil || !existing.ExpiresAt.IsZero() {
+			continue
+		}
+		if existing.ActiveFrom.Before(activateAt) {
+			k.keys[i].ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+
+// Prune removes expired keys and returns how many were removed.
+func (k *Keyring) Prune() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	kept := k.keys[:0]
+	for _, key := range k.keys {
+		if !key.expired(now) {
+			kept = append(kept, key)
+		}
+	}
+	removed := len(k.keys) - len(kept)
+	k.keys = kept
+	return removed
+}
+
+// SigningKey returns the key tokens are signed with: the active key with a
+// private key that was activated last.
+func (k *Keyring) SigningKey() (Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := k.now()
+	var current Key
+	found := false
+	for _, key := range k.keys {
+		if key.PrivateKey == nil || key.expired(now) || key.ActiveFrom.After(now) {
+			continue
+		}
+		if !found || !key.ActiveFrom.Before(current.ActiveFrom) {
+			current, found = key, true
+		}
+	}
+	return current, found
+}
+
+// VerificationKey returns the unexpired key with id.
+func (k *Keyring) VerificationKey(id string) (Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := k.now()
+	for _, key := range k.keys {
+		if key.ID == id && !key.expired(now) {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Keys returns the unexpired keys, including those scheduled to sign later.
+func (k *Keyring) Keys() []Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := k.now()
+	keys := make([]Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (key Key) expired(now time.Time) bool {
+	return !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt)
+}
+
+// RotationPolicy configures Keyring.AutoRotate.
+type RotationPolicy struct {
+	// Algorithm of the generated keys.
+	Algorithm string
+	// Interval between rotations.
+	Interval time.Duration
+	// Overlap is how long a replaced key keeps verifying. It should be at
+	// least the token expiry.
+	Overlap time.Duration
+	// Lead is how long a new key is published before it starts signing,
+	// so verifiers that cache the JWKS pick it up first.
+	Lead time.Duration
+}
+
+// AutoRotate generates a new signing key every Interval and prunes expired
+// keys until ctx is cancelled. It returns an error if a key can't be
+// generated.
+func (k *Keyring) AutoRotate(ctx context.Context, policy RotationPolicy) error {
+	if policy.Interval <= 0 {
+		return errors.New("rotation interval must be positive")
+	}
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		key, err := GenerateKey(policy.Algorithm)
+		if err != nil {
+			return fmt.Errorf("rotating keys: %w", err)
+		}
+		if err := k.Rotate(key, k.now().Add(policy.Lead), policy.Overlap); err != nil {
+			return fmt.Errorf("rotating keys: %w", err)
+		}
+		k.Prune()
+	}
+}
@@ -0,0 +1,130 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeyring_Rotate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldKey, _ := GenerateKey(AlgES256)
+	newKey, _ := GenerateKey(AlgES256)
+
+	keys, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	keys.now = func() time.Time { return now }
+	svc := NewServiceWithKeyring(keys, "auth", "1h")
+
+	oldToken, err := svc.GenerateToken("user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	if err := keys.Rotate(newKey, now.Add(time.Hour), 2*time.Hour); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	if current, _ := keys.SigningKey(); current.ID != oldKey.ID {
+		t.Errorf("expected old key to sign until the rotation, got %s", current.ID)
+	}
+	if len(keys.JWKS().Keys) != 2 {
+		t.Errorf("expected the new key to be published ahead of the rotation")
+	}
+
+	// After the rotation, within the overlap
+	now = now.Add(90 * time.Minute)
+	if current, _ := keys.SigningKey(); current.ID != newKey.ID {
+		t.Errorf("expected new key to sign after the rotation, got %s", current.ID)
+	}
+	if _, ok := keys.VerificationKey(oldKey.ID); !ok {
+		t.Error("expected old key to verify during the overlap")
+	}
+
+	// After the overlap
+	now = now.Add(2 * time.Hour)
+	if _, ok := keys.VerificationKey(oldKey.ID); ok {
+		t.Error("expected old key to stop verifying after the overlap")
+	}
+	if _, err := svc.ValidateToken(oldToken); err == nil {
+		t.Error("expected tokens of the expired key to be rejected")
+	}
+	if removed := keys.Prune(); removed != 1 {
+		t.Errorf("expected 1 pruned key, got %d", removed)
+	}
+}
+
+func TestKeyring_AutoRotate(t *testing.T) {
+	first, _ := GenerateKey(AlgEdDSA)
+	keys, err := NewKeyring(first)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = keys.AutoRotate(ctx, RotationPolicy{Algorithm: AlgEdDSA, Interval: 20 * time.Millisecond, Overlap: time.Hour})
+	if err != nil {
+		t.Fatalf("auto rotation failed: %v", err)
+	}
+
+	current, ok := keys.SigningKey()
+	if !ok || current.ID == first.ID {
+		t.Error("expected a generated key to be signing")
+	}
+	if _, ok := keys.VerificationKey(first.ID); !ok {
+		t.Error("expected the first key to verify during the overlap")
+	}
+}
+
+func TestKeyring_DuplicateID(t *testing.T) {
+	a, _ := GenerateKey(AlgHS256)
+	b, _ := GenerateKey(AlgHS256)
+	b.ID = a.ID
+	if _, err := NewKeyring(a, b); err == nil {
+		t.Error("expected error for duplicate key ids")
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	rsaKey, _ := GenerateKey(AlgRS256)
+	ecKey, _ := GenerateKey(AlgES256)
+	edKey, _ := GenerateKey(AlgEdDSA)
+	hsKey, _ := GenerateKey(AlgHS256)
+	keys, err := NewKeyring(rsaKey, ecKey, edKey, hsKey)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	JWKSHandler(keys).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var set JWKS
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatalf("failed to decode jwks: %v", err)
+	}
+	if len(set.Keys) != 3 {
+		t.Fatalf("expected 3 public keys without the HMAC secret, got %d", len(set.Keys))
+	}
+
+	want := map[string]string{rsaKey.ID: "RSA", ecKey.ID: "EC", edKey.ID: "OKP"}
+	for _, jwk := range set.Keys {
+		if want[jwk.KeyID] != jwk.KeyType {
+			t.Errorf("expected key %s of type %s, got %s", jwk.KeyID, want[jwk.KeyID], jwk.KeyType)
+		}
+		if jwk.KeyType == "EC" && (jwk.Curve != "P-256" || len(jwk.X) != 43 || len(jwk.Y) != 43) {
+			t.Errorf("expected padded P-256 coordinates, got %+v", jwk)
+		}
+		if jwk.KeyType == "RSA" && jwk.E != "AQAB" {
+			t.Errorf("expected exponent AQAB, got %s", jwk.E)
+		}
+	}
+}
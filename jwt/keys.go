@@ -0,0 +1,244 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of RSA keys created by GenerateKey, and the minimum
+// size of RSA keys read from a JWKS.
+const rsaKeyBits = 2048
+
+// Key is a key of a Keyring. HS256 keys hold the shared secret as a []byte
+// in both PrivateKey and PublicKey. Asymmetric keys hold an *rsa, *ecdsa or
+// ed25519 key pair; verification-only keys have no PrivateKey.
+type Key struct {
+	// ID is sent as the "kid" header of the tokens the key signs.
+	ID        string
+	Algorithm string
+
+	PrivateKey any
+	PublicKey  any
+
+	// ActiveFrom is when the key starts signing; zero means right away.
+	// ExpiresAt is when it stops verifying; zero means never.
+	ActiveFrom time.Time
+	ExpiresAt  time.Time
+}
+
+// signingMethod returns the signing method of alg.
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgHS256:
+		return jwt.SigningMethodHS256, nil
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgES256:
+		return jwt.SigningMethodES256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm [%s]", alg)
+	}
+}
+
+// validate checks that the key material matches the algorithm.
+func (k Key) validate() error {
+	if _, err := signingMethod(k.Algorithm); err != nil {
+		return err
+	}
+	if k.PublicKey == nil {
+		return fmt.Errorf("key [%s] has no public key", k.ID)
+	}
+
+	var ok bool
+	switch k.Algorithm {
+	case AlgHS256:
+		secret, isBytes := k.PublicKey.([]byte)
+		ok = isBytes && len(secret) > 0
+	case AlgRS256:
+		_, ok = k.PublicKey.(*rsa.PublicKey)
+		if k.PrivateKey != nil {
+			_, privOK := k.PrivateKey.(*rsa.PrivateKey)
+			ok = ok && privOK
+		}
+	case AlgES256:
+		pub, isEC := k.PublicKey.(*ecdsa.PublicKey)
+		ok = isEC && pub.Curve == elliptic.P256()
+		if k.PrivateKey != nil {
+			_, privOK := k.PrivateKey.(*ecdsa.PrivateKey)
+			ok = ok && privOK
+		}
+	case AlgEdDSA:
+		_, ok = k.PublicKey.(ed25519.PublicKey)
+		if k.PrivateKey != nil {
+			_, privOK := k.PrivateKey.(ed25519.PrivateKey)
+			ok = ok && privOK
+		}
+	}
+	if !ok {
+		return fmt.Errorf("key [%s] does not match algorithm [%s]", k.ID, k.Algorithm)
+	}
+	return nil
+}
+
+// GenerateKey creates a key for alg with a random ID.
+func GenerateKey(alg string) (Key, error) {
+	key := Key{ID: uuid.Must(uuid.NewV4()).String(), Algorithm: alg}
+
+	switch alg {
+	case AlgHS256:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return Key{}, fmt.Errorf("generating secret: %w", err)
+		}
+		key.PrivateKey, key.PublicKey = secret, secret
+	case AlgRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return Key{}, fmt.Errorf("generating rsa key: %w", err)
+		}
+		key.PrivateKey, key.PublicKey = priv, &priv.PublicKey
+	case AlgES256:
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return Key{}, fmt.Errorf("generating ecdsa key: %w", err)
+		}
+		key.PrivateKey, key.PublicKey = priv, &priv.PublicKey
+	case AlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Key{}, fmt.Errorf("generating ed25519 key: %w", err)
+		}
+		key.PrivateKey, key.PublicKey = priv, pub
+	default:
+		return Key{}, fmt.Errorf("unsupported algorithm [%s]", alg)
+	}
+
+	return key, nil
+}
+
+// ParsePrivateKeyPEM returns a signing key for alg from a PEM-encoded
+// private key. The public key is derived from it.
+func ParsePrivateKeyPEM(alg, id string, data []byte) (Key, error) {
+	key := Key{ID: id, Algorithm: alg}
+
+	switch alg {
+	case AlgRS256:
+		priv, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return Key{}, fmt.Errorf("parsing rsa private key: %w", err)
+		}
+		key.PrivateKey, key.PublicKey = priv, &priv.PublicKey
+	case AlgES256:
+		priv, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return Key{}, fmt.Errorf("parsing ecdsa private key: %w", err)
+		}
+		key.PrivateKey, key.PublicKey = priv, &priv.PublicKey
+	case AlgEdDSA:
+		priv, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return Key{}, fmt.Errorf("parsing ed25519 private key: %w", err)
+		}
+		edPriv, ok := priv.(ed25519.PrivateKey)
+		if !ok {
+			return Key{}, errors.New("parsing ed25519 private key: not an ed25519 key")
+		}
+		key.PrivateKey, key.PublicKey = edPriv, edPriv.Public()
+	default:
+		return Key{}, fmt.Errorf("algorithm [%s] has no private key", alg)
+	}
+
+	return key, key.validate()
+}
+
+// ParsePublicKeyPEM returns a verification-only key for alg from a
+// PEM-encoded public key.
+func ParsePublicKeyPEM(alg, id string, data []byte) (Key, error) {
+	key := Key{ID: id, Algorithm: alg}
+
+	var err error
+	switch alg {
+	case AlgRS256:
+		key.PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(data)
+	case AlgES256:
+		key.PublicKey, err = jwt.ParseECPublicKeyFromPEM(data)
+	case AlgEdDSA:
+		key.PublicKey, err = jwt.ParseEdPublicKeyFromPEM(data)
+	default:
+		return Key{}, fmt.Errorf("algorithm [%s] has no public key", alg)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("parsing public key: %w", err)
+	}
+
+	return key, key.validate()
+}
+
+// LoadKey returns the key described by cfg: the secret for HS256, or the
+// private key (or, for verification-only services, the public key) read
+// from the PEM value or file for the other algorithms.
+func LoadKey(cfg Config) (Key, error) {
+	alg := cfg.Algorithm
+	if alg == "" {
+		alg = AlgHS256
+	}
+
+	if alg == AlgHS256 {
+		key := Key{ID: cfg.KeyID, Algorithm: alg, PrivateKey: []byte(cfg.SecretKey), PublicKey: []byte(cfg.SecretKey)}
+		return key, key.validate()
+	}
+
+	private, err := pemValue(cfg.PrivateKey, cfg.PrivateKeyFile)
+	if err != nil {
+		return Key{}, err
+	}
+	if private != nil {
+		return ParsePrivateKeyPEM(alg, cfg.KeyID, private)
+	}
+
+	public, err := pemValue(cfg.PublicKey, cfg.PublicKeyFile)
+	if err != nil {
+		return Key{}, err
+	}
+	if public != nil {
+		return ParsePublicKeyPEM(alg, cfg.KeyID, public)
+	}
+
+	return Key{}, errors.New("no private or public key configured")
+}
+
+// pemValue returns value, or the contents of file when value is empty.
+func pemValue(value, file string) ([]byte, error) {
+	if value != "" {
+		return []byte(value), nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file [%s]: %w", file, err)
+	}
+	return data, nil
+}
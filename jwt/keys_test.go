@@ -0,0 +1,139 @@
+package jwt
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM writes the PEM encoding of key's private and public halves and
+// returns their paths.
+func writePEM(t *testing.T, key Key) (string, string) {
+	t.Helper()
+
+	priv, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	dir := t.TempDir()
+	privPath, pubPath := filepath.Join(dir, "key.pem"), filepath.Join(dir, "key.pub.pem")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0o600); err != nil {
+		t.Fatalf("failed to write private key: %v", err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0o600); err != nil {
+		t.Fatalf("failed to write public key: %v", err)
+	}
+	return privPath, pubPath
+}
+
+func TestAsymmetricAlgorithms(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgES256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			generated, err := GenerateKey(alg)
+			if err != nil {
+				t.Fatalf("failed to generate key: %v", err)
+			}
+			privPath, pubPath := writePEM(t, generated)
+
+			issuer, err := LoadService(Config{Algorithm: alg, PrivateKeyFile: privPath, KeyID: "key-1", Issuer: "auth", Expiry: "1h"})
+			if err != nil {
+				t.Fatalf("failed to create issuing service: %v", err)
+			}
+			pubPEM, err := os.ReadFile(pubPath)
+			if err != nil {
+				t.Fatalf("failed to read public key: %v", err)
+			}
+			verifier, err := LoadService(Config{Algorithm: alg, PublicKey: string(pubPEM), KeyID: "key-1", Issuer: "auth", Expiry: "1h"})
+			if err != nil {
+				t.Fatalf("failed to create verifying service: %v", err)
+			}
+
+			token, err := issuer.GenerateToken("user-1", "test@example.com", "admin")
+			if err != nil {
+				t.Fatalf("failed to generate token: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			if parsed.Header["alg"] != alg || parsed.Header["kid"] != "key-1" {
+				t.Errorf("expected alg %s and kid key-1, got %v", alg, parsed.Header)
+			}
+
+			claims, err := verifier.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("failed to validate token with public key: %v", err)
+			}
+			if claims.UserID != "user-1" {
+				t.Errorf("expected user-1, got %s", claims.UserID)
+			}
+
+			if _, err := verifier.GenerateToken("user-1", "test@example.com", "admin"); err == nil {
+				t.Error("expected verification-only service not to issue tokens")
+			}
+		})
+	}
+}
+
+func TestValidateToken_AlgorithmConfusion(t *testing.T) {
+	key, err := GenerateKey(AlgRS256)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keys, err := NewKeyring(Key{ID: "rsa", Algorithm: AlgRS256, PublicKey: key.PublicKey})
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	svc := NewServiceWithKeyring(keys, "auth", "1h")
+
+	// An HS256 token signed with the public key bytes must not verify
+	pub, _ := x509.MarshalPKIXPublicKey(key.PublicKey)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "attacker"})
+	forged.Header["kid"] = "rsa"
+	token, err := forged.SignedString(pub)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, err := svc.ValidateToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for mismatched algorithm, got %v", err)
+	}
+}
+
+func TestLoadKey_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "unknown algorithm", cfg: Config{Algorithm: "PS512", PublicKey: "x"}},
+		{name: "no key", cfg: Config{Algorithm: AlgES256}},
+		{name: "empty secret", cfg: Config{Algorithm: AlgHS256}},
+		{name: "missing file", cfg: Config{Algorithm: AlgRS256, PrivateKeyFile: "/nonexistent/key.pem"}},
+		{name: "invalid pem", cfg: Config{Algorithm: AlgEdDSA, PrivateKey: "not a pem"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKey(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	rsaKey, _ := GenerateKey(AlgRS256)
+	privPath, _ := writePEM(t, rsaKey)
+	if _, err := LoadKey(Config{Algorithm: AlgES256, PrivateKeyFile: privPath}); err == nil {
+		t.Error("expected error for an rsa key configured as ES256")
+	}
+}
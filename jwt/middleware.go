@@ -0,0 +1,228 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// TokenValidator validates a token and returns its claims. Service
+// implements it.
+type TokenValidator interface {
+	ValidateToken(token string) (*Claims, error)
+}
+
+// ContextTokenValidator is a TokenValidator that also validates with a
+// context, for lookups such as revocation checks and key fetches. Service
+// and Verifier implement it, and Authenticate uses it with the request
+// context.
+type ContextTokenValidator interface {
+	TokenValidator
+	ValidateTokenContext(ctx context.Context, token string) (*Claims, error)
+}
+
+// validateToken validates token with ctx when validator supports it.
+func validateToken(ctx context.Context, validator TokenValidator, token string) (*Claims, error) {
+	if cv, ok := validator.(ContextTokenValidator); ok {
+		return cv.ValidateTokenContext(ctx, token)
+	}
+	return validator.ValidateToken(token)
+}
+
+// AuthConfig configures Authenticate.
+type AuthConfig struct {
+	// CookieName is the cookie read when the request has no Authorization
+	// header. Empty only reads the header.
+	CookieName string
+	// Realm is sent in WWW-Authenticate challenges.
+	Realm string
+	// Optional lets requests without a token through unauthenticated.
+	// Requests with an invalid token are still rejected.
+	Optional bool
+}
+
+// Requirement checks the claims of an authenticated request. Returning an
+// error rejects the request with 403 Forbidden.
+type Requirement func(claims *Claims) error
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying claims.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by Authenticate.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// UserIDFromContext returns the user ID of the authenticated request, or
+// "" if there is none.
+func UserIDFromContext(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.UserID
+	}
+	return ""
+}
+
+// EmailFromContext returns the email of the authenticated request, or ""
+// if there is none.
+func EmailFromContext(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.Email
+	}
+	return ""
+}
+
+// AccountTypeFromContext returns the account type of the authenticated
+// request, or "" if there is none.
+func AccountTypeFromContext(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.AccountType
+	}
+	return ""
+}
+
+// Authenticate validates the bearer token of each request with validator
+// and stores its claims in the request context. The token is read from the
+// Authorization header, or from the cookie named in cfg. Requests without a
+// valid token get 401 Unauthorized with an RFC 6750 WWW-Authenticate
+// challenge.
+func Authenticate(validator TokenValidator, cfg AuthConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := bearerToken(r, cfg.CookieName)
+			if err != nil {
+				challenge(w, cfg.Realm, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+			if token == "" {
+				if cfg.Optional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				challenge(w, cfg.Realm, http.StatusUnauthorized, "", "")
+				return
+			}
+
+			claims, err := validateToken(r.Context(), validator, token)
+			if err != nil {
+				// Parser details stay out of the response
+				description := string(ErrInvalidToken)
+				switch {
+				case errors.Is(err, ErrTokenExpired):
+					description = string(ErrTokenExpired)
+				case errors.Is(err, ErrTokenRevoked):
+					description = string(ErrTokenRevoked)
+				case errors.Is(err, ErrTokenNotValidYet):
+					description = string(ErrTokenNotValidYet)
+				}
+				challenge(w, cfg.Realm, http.StatusUnauthorized, "invalid_token", description)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// Require rejects requests whose claims fail any of requirements with 403
+// Forbidden, and requests that were not authenticated with 401
+// Unauthorized. It goes after Authenticate, typically on single routes.
+func Require(realm string, requirements ...Requirement) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				challenge(w, realm, http.StatusUnauthorized, "", "")
+				return
+			}
+
+			for _, req := range requirements {
+				if err := req(claims); err != nil {
+					challenge(w, realm, http.StatusForbidden, "insufficient_scope", err.Error())
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAccountType requires the account type to be one of types.
+func RequireAccountType(types ...string) Requirement {
+	return func(claims *Claims) error {
+		if slices.Contains(types, claims.AccountType) {
+			return nil
+		}
+		return fmt.Errorf("%w: account type [%s] is not allowed", ErrInsufficientScope, claims.AccountType)
+	}
+}
+
+// RequireScopes requires the "scope" claim to grant every one of scopes.
+func RequireScopes(scopes ...string) Requirement {
+	return func(claims *Claims) error {
+		granted := claims.Scopes()
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return fmt.Errorf("%w: scope [%s] is required", ErrInsufficientScope, scope)
+			}
+		}
+		return nil
+	}
+}
+
+// bearerToken returns the token of r, or "" when it carries none. A
+// malformed Authorization header is an error; other schemes are ignored.
+func bearerToken(r *http.Request, cookieName string) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return "", nil
+		}
+		token = strings.TrimSpace(token)
+		if token == "" || strings.Contains(token, " ") {
+			return "", errors.New("malformed authorization header")
+		}
+		return token, nil
+	}
+
+	if cookieName != "" {
+		if c, err := r.Cookie(cookieName); err == nil {
+			return c.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// challenge writes an RFC 6750 error response. A request without
+// credentials gets a challenge without an error code.
+func challenge(w http.ResponseWriter, realm string, status int, code, description string) {
+	var params []string
+	if realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", realm))
+	}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", description))
+	}
+
+	value := "Bearer"
+	if len(params) > 0 {
+		value += " " + strings.Join(params, ", ")
+	}
+	w.Header().Set("WWW-Authenticate", value)
+
+	if description == "" {
+		description = http.StatusText(status)
+	}
+	http.Error(w, description, status)
+}
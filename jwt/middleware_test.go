@@ -0,0 +1,123 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthenticate(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "1h")
+	valid, err := svc.GenerateToken("user-1", "test@example.com", "admin")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	expired, err := NewService("test-secret", "test-issuer", "-1m").GenerateToken("user-1", "test@example.com", "admin")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		header        string
+		cookie        string
+		optional      bool
+		wantStatus    int
+		wantChallenge string
+		wantUser      string
+	}{
+		{name: "header", header: "Bearer " + valid, wantStatus: 200, wantUser: "user-1"},
+		{name: "lower case scheme", header: "bearer " + valid, wantStatus: 200, wantUser: "user-1"},
+		{name: "cookie", cookie: valid, wantStatus: 200, wantUser: "user-1"},
+		{name: "missing", wantStatus: 401, wantChallenge: `Bearer realm="api"`},
+		{name: "other scheme", header: "Basic dXNlcjpwYXNz", wantStatus: 401, wantChallenge: `Bearer realm="api"`},
+		{name: "malformed", header: "Bearer ", wantStatus: 400, wantChallenge: `Bearer realm="api", error="invalid_request", error_description="malformed authorization header"`},
+		{name: "invalid", header: "Bearer not-a-token", wantStatus: 401, wantChallenge: `Bearer realm="api", error="invalid_token", error_description="invalid token"`},
+		{name: "expired", header: "Bearer " + expired, wantStatus: 401, wantChallenge: `Bearer realm="api", error="invalid_token", error_description="token has expired"`},
+		{name: "optional without token", optional: true, wantStatus: 200},
+		{name: "optional with invalid token", optional: true, header: "Bearer not-a-token", wantStatus: 401, wantChallenge: `Bearer realm="api", error="invalid_token", error_description="invalid token"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUser string
+			h := Authenticate(svc, AuthConfig{CookieName: "session", Realm: "api", Optional: tt.optional})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					gotUser = UserIDFromContext(r.Context())
+				}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("expected challenge %q, got %q", tt.wantChallenge, got)
+			}
+			if gotUser != tt.wantUser {
+				t.Errorf("expected user %q in context, got %q", tt.wantUser, gotUser)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	handler := Require("api", RequireAccountType("admin"), func(c *Claims) error {
+		if c.Email == "" {
+			return errors.New("email required")
+		}
+		return nil
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		claims     *Claims
+		wantStatus int
+	}{
+		{name: "allowed", claims: &Claims{AccountType: "admin", Email: "a@example.com"}, wantStatus: 200},
+		{name: "wrong account type", claims: &Claims{AccountType: "user", Email: "a@example.com"}, wantStatus: 403},
+		{name: "custom requirement", claims: &Claims{AccountType: "admin"}, wantStatus: 403},
+		{name: "unauthenticated", wantStatus: 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.claims != nil {
+				req = req.WithContext(ContextWithClaims(req.Context(), tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				want := `error="insufficient_scope"`
+				if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, want) {
+					t.Errorf("expected challenge with %s, got %q", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireAccountType_Error(t *testing.T) {
+	err := RequireAccountType("admin")(&Claims{AccountType: "user"})
+	if !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("expected ErrInsufficientScope, got %v", err)
+	}
+	if ErrInsufficientScope.StatusCode() != http.StatusForbidden {
+		t.Errorf("expected 403 status, got %d", ErrInsufficientScope.StatusCode())
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/guilhermebr/gox/jwt/pgstore
+
+go 1.24.3
+
+require (
+	github.com/guilhermebr/gox/jwt v0.0.0
+	github.com/jackc/pgx/v5 v5.7.5
+)
+
+require (
+	github.com/ardanlabs/conf/v3 v3.8.0 // indirect
+	github.com/gofrs/uuid/v5 v5.3.2 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/text v0.24.0 // indirect
+)
+
+// Until a jwt release includes the refresh and revocation store interfaces
+replace github.com/guilhermebr/gox/jwt => ../
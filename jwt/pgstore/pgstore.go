@@ -0,0 +1,149 @@
+// Package pgstore keeps jwt refresh tokens in Postgres.
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/guilhermebr/gox/jwt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RefreshSchema creates the refresh token table. Run it with your
+// migrations, or call CreateSchema.
+const RefreshSchema = `CREATE TABLE IF NOT EXISTS jwt_refresh_tokens (
+	id         TEXT PRIMARY KEY,
+	family_id  TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	claims     JSONB NOT NULL,
+	access_token_id TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at    TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS jwt_refresh_tokens_family_id_idx ON jwt_refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS jwt_refresh_tokens_expires_at_idx ON jwt_refresh_tokens (expires_at);`
+
+// DB runs queries. *pgxpool.Pool, *postgres.DatabasePool and pgx.Tx
+// implement it.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// RefreshStore is a jwt.RefreshStore shared by every instance using the
+// database.
+type RefreshStore struct {
+	db DB
+}
+
+var _ jwt.RefreshStore = (*RefreshStore)(nil)
+
+func NewRefreshStore(db DB) *RefreshStore {
+	return &RefreshStore{db: db}
+}
+
+// CreateSchema creates the refresh token table if it does not exist.
+func (s *RefreshStore) CreateSchema(ctx context.Context) error {
+	if _, err := s.db.Exec(ctx, RefreshSchema); err != nil {
+		return fmt.Errorf("creating refresh token schema: %w", err)
+	}
+	return nil
+}
+
+func (s *RefreshStore) Create(ctx context.Context, rec jwt.RefreshRecord) error {
+	_, err := s.db.Exec(ctx, `INSERT INTO jwt_refresh_tokens
+		(id, family_id, user_id, claims, access_token_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		rec.ID, rec.FamilyID, rec.UserID, string(rec.Claims), rec.AccessTokenID, rec.CreatedAt, rec.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("inserting refresh token: %w", err)
+	}
+	return nil
+}
+
+// Consume locks the row so concurrent exchanges of one token are
+// serialized, and returns it as it was before the update.
+func (s *RefreshStore) Consume(ctx context.Context, id string, now time.Time) (jwt.RefreshRecord, error) {
+	rec, err := scanRecord(s.db.QueryRow(ctx, `WITH old AS (
+			SELECT * FROM jwt_refresh_tokens WHERE id = $1 FOR UPDATE
+		)
+		UPDATE jwt_refresh_tokens t SET used_at = COALESCE(t.used_at, $2)
+		FROM old WHERE t.id = old.id
+		RETURNING `+oldColumns, id, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return jwt.RefreshRecord{}, fmt.Errorf("unknown refresh token: %w", jwt.ErrInvalidToken)
+	}
+	if err != nil {
+		return jwt.RefreshRecord{}, fmt.Errorf("consuming refresh token: %w", err)
+	}
+	return rec, nil
+}
+
+func (s *RefreshStore) RevokeFamily(ctx context.Context, familyID string, now time.Time) ([]jwt.RefreshRecord, error) {
+	rows, err := s.db.Query(ctx, `WITH old AS (
+			SELECT * FROM jwt_refresh_tokens
+			WHERE family_id = $1 AND revoked_at IS NULL FOR UPDATE
+		)
+		UPDATE jwt_refresh_tokens t SET revoked_at = $2
+		FROM old WHERE t.id = old.id
+		RETURNING `+oldColumns, familyID, now)
+	if err != nil {
+		return nil, fmt.Errorf("revoking refresh token family [%s]: %w", familyID, err)
+	}
+	defer rows.Close()
+
+	var revoked []jwt.RefreshRecord
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("revoking refresh token family [%s]: %w", familyID, err)
+		}
+		revoked = append(revoked, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("revoking refresh token family [%s]: %w", familyID, err)
+	}
+	return revoked, nil
+}
+
+// oldColumns are the columns of a record before an update, as read by
+// scanRecord.
+const oldColumns = `old.id, old.family_id, old.user_id, old.claims, old.access_token_id,
+	old.created_at, old.expires_at, old.used_at, old.revoked_at`
+
+func scanRecord(row pgx.Row) (jwt.RefreshRecord, error) {
+	var (
+		rec               jwt.RefreshRecord
+		claims            []byte
+		usedAt, revokedAt *time.Time
+	)
+	if err := row.Scan(&rec.ID, &rec.FamilyID, &rec.UserID, &claims, &rec.AccessTokenID,
+		&rec.CreatedAt, &rec.ExpiresAt, &usedAt, &revokedAt); err != nil {
+		return jwt.RefreshRecord{}, err
+	}
+
+	rec.Claims = claims
+	if usedAt != nil {
+		rec.UsedAt = *usedAt
+	}
+	if revokedAt != nil {
+		rec.RevokedAt = *revokedAt
+	}
+	return rec, nil
+}
+
+// Prune deletes the tokens that expired before now and returns how many
+// were deleted.
+func (s *RefreshStore) Prune(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM jwt_refresh_tokens WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("pruning refresh tokens: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
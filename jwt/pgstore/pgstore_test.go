@@ -0,0 +1,120 @@
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/guilhermebr/gox/jwt"
+	"github.com/jackc/pgx/v5"
+)
+
+// testTx returns a transaction on the database at PGSTORE_TEST_DATABASE_URL
+// that is rolled back when the test ends, so the database is untouched.
+func testTx(t *testing.T) pgx.Tx {
+	t.Helper()
+	url := os.Getenv("PGSTORE_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("Skipping store test - set PGSTORE_TEST_DATABASE_URL to run it against a database")
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = tx.Rollback(ctx)
+		_ = conn.Close(ctx)
+	})
+	return tx
+}
+
+func TestRefreshStore(t *testing.T) {
+	ctx := context.Background()
+	tx := testTx(t)
+	store := NewRefreshStore(tx)
+	if err := store.CreateSchema(ctx); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	revocations := NewRevocationStore(tx)
+	if err := revocations.CreateSchema(ctx); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	svc := jwt.NewService("test-secret", "test-issuer", "15m").
+		WithRefreshStore(store).
+		WithRevocationStore(revocations)
+
+	first, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user", "orders:read")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	other, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	claims, err := svc.ValidateToken(second.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate refreshed access token: %v", err)
+	}
+	if claims.Email != "test@example.com" || claims.Scope != "orders:read" {
+		t.Errorf("expected claims to survive the refresh, got %+v", claims)
+	}
+	if _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, jwt.ErrRefreshTokenReused) {
+		t.Fatalf("expected reuse to be detected, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken); !errors.Is(err, jwt.ErrInvalidToken) {
+		t.Errorf("expected the family to be revoked, got %v", err)
+	}
+	if _, err := svc.ValidateToken(second.AccessToken); !errors.Is(err, jwt.ErrTokenRevoked) {
+		t.Errorf("expected the family's access tokens to be revoked, got %v", err)
+	}
+	if _, err := svc.ValidateToken(other.AccessToken); err != nil {
+		t.Errorf("expected the other session to stay valid, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, "unknown"); !errors.Is(err, jwt.ErrInvalidToken) {
+		t.Errorf("expected unknown token to be rejected, got %v", err)
+	}
+}
+
+func TestRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewRevocationStore(testTx(t))
+	if err := store.CreateSchema(ctx); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	svc := jwt.NewService("test-secret", "test-issuer", "1h").WithRevocationStore(store)
+
+	token, _ := svc.GenerateToken("user-1", "test@example.com", "user")
+	if err := svc.RevokeToken(ctx, token); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if _, err := svc.ValidateToken(token); !errors.Is(err, jwt.ErrTokenRevoked) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+
+	other, _ := svc.GenerateToken("user-2", "other@example.com", "user")
+	if err := svc.RevokeSubject(ctx, "user-2", time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to revoke subject: %v", err)
+	}
+	if err := svc.RevokeSubject(ctx, "user-2", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to revoke subject: %v", err)
+	}
+	if _, err := svc.ValidateToken(other); !errors.Is(err, jwt.ErrTokenRevoked) {
+		t.Errorf("expected tokens before the cutoff to be rejected, got %v", err)
+	}
+
+	if n, err := store.Prune(ctx, time.Now().Add(24*time.Hour*31)); err != nil || n != 2 {
+		t.Errorf("expected 2 pruned entries, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,92 @@
+package pgstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/guilhermebr/gox/jwt"
+)
+
+// RevocationSchema creates the revocation tables. Run it with your
+// migrations, or call CreateSchema.
+const RevocationSchema = `CREATE TABLE IF NOT EXISTS jwt_revoked_tokens (
+	jti        TEXT PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS jwt_revoked_subjects (
+	subject        TEXT PRIMARY KEY,
+	revoked_before TIMESTAMPTZ NOT NULL,
+	expires_at     TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS jwt_revoked_tokens_expires_at_idx ON jwt_revoked_tokens (expires_at);
+CREATE INDEX IF NOT EXISTS jwt_revoked_subjects_expires_at_idx ON jwt_revoked_subjects (expires_at);`
+
+// RevocationStore is a jwt.RevocationStore shared by every instance using
+// the database. Subject cutoffs are kept to the microsecond, the precision
+// of TIMESTAMPTZ.
+type RevocationStore struct {
+	db DB
+}
+
+var _ jwt.RevocationStore = (*RevocationStore)(nil)
+
+func NewRevocationStore(db DB) *RevocationStore {
+	return &RevocationStore{db: db}
+}
+
+// CreateSchema creates the revocation tables if they do not exist.
+func (s *RevocationStore) CreateSchema(ctx context.Context) error {
+	if _, err := s.db.Exec(ctx, RevocationSchema); err != nil {
+		return fmt.Errorf("creating revocation schema: %w", err)
+	}
+	return nil
+}
+
+func (s *RevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := s.db.Exec(ctx, `INSERT INTO jwt_revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("inserting revoked token: %w", err)
+	}
+	return nil
+}
+
+func (s *RevocationStore) RevokeSubject(ctx context.Context, subject string, before, expiresAt time.Time) error {
+	_, err := s.db.Exec(ctx, `INSERT INTO jwt_revoked_subjects (subject, revoked_before, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (subject) DO UPDATE SET
+			revoked_before = GREATEST(jwt_revoked_subjects.revoked_before, EXCLUDED.revoked_before),
+			expires_at = GREATEST(jwt_revoked_subjects.expires_at, EXCLUDED.expires_at)`,
+		subject, before, expiresAt)
+	if err != nil {
+		return fmt.Errorf("inserting revoked subject: %w", err)
+	}
+	return nil
+}
+
+func (s *RevocationStore) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := s.db.QueryRow(ctx, `SELECT
+		EXISTS (SELECT 1 FROM jwt_revoked_tokens WHERE jti = $1 AND $1 <> '')
+		OR EXISTS (SELECT 1 FROM jwt_revoked_subjects WHERE subject = $2 AND $2 <> '' AND revoked_before > $3)`,
+		jti, subject, issuedAt).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("checking revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// Prune deletes the entries that expired before now and returns how many
+// were deleted.
+func (s *RevocationStore) Prune(ctx context.Context, now time.Time) (int64, error) {
+	tokens, err := s.db.Exec(ctx, `DELETE FROM jwt_revoked_tokens WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("pruning revoked tokens: %w", err)
+	}
+	subjects, err := s.db.Exec(ctx, `DELETE FROM jwt_revoked_subjects WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("pruning revoked subjects: %w", err)
+	}
+	return tokens.RowsAffected() + subjects.RowsAffected(), nil
+}
@@ -0,0 +1,283 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// defaultRefreshExpiry is the refresh token lifetime when none is set.
+const defaultRefreshExpiry = 30 * 24 * time.Hour
+
+// RefreshRecord is a refresh token as kept by a RefreshStore. Tokens are
+// stored by the SHA-256 of their value, never the value itself.
+type RefreshRecord struct {
+	ID string
+	// FamilyID is shared by every token rotated from the same sign-in.
+	FamilyID string
+	UserID   string
+	// Claims are the custom claims of the access tokens issued with the
+	// token, as a JSON object, so they survive a refresh.
+	Claims json.RawMessage
+	// AccessTokenID is the jti of the access token issued with the token,
+	// revoked along with the family.
+	AccessTokenID string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	// UsedAt is set when the token is exchanged; a token used twice has
+	// been stolen.
+	UsedAt    time.Time
+	RevokedAt time.Time
+}
+
+// RefreshStore keeps refresh tokens. MemoryRefreshStore keeps them in
+// memory; the pgstore package keeps them in Postgres.
+type RefreshStore interface {
+	Create(ctx context.Context, rec RefreshRecord) error
+	// Consume marks the token with id as used at now and returns it as it
+	// was before, so concurrent exchanges of one token see it used at most
+	// once. Unknown ids return ErrInvalidToken.
+	Consume(ctx context.Context, id string, now time.Time) (RefreshRecord, error)
+	// RevokeFamily revokes every token of a family at now and returns the
+	// tokens it revoked, as they were before.
+	RevokeFamily(ctx context.Context, familyID string, now time.Time) ([]RefreshRecord, error)
+}
+
+// TokenPair is an access token with the refresh token that renews it.
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// WithRefreshStore returns a copy of the service that issues refresh
+// tokens kept in store.
+func (s Service) WithRefreshStore(store RefreshStore) Service {
+	s.refresh = store
+	return s
+}
+
+// IssueTokens signs in a user granted scopes, returning an access token and
+// a refresh token that starts a new family. Use the IssueTokens function
+// for tokens with other claims.
+func (s Service) IssueTokens(ctx context.Context, userID, email, accountType string, scopes ...string) (TokenPair, error) {
+	return IssueTokens(ctx, s, userID, userClaims{
+		UserID:      userID,
+		Email:       email,
+		AccountType: accountType,
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// IssueTokens signs in subject, returning an access token with custom
+// claims and a refresh token that starts a new family. The claims are kept
+// with the refresh token, and the access tokens it renews carry them too.
+func IssueTokens[T any](ctx context.Context, s Service, subject string, custom T) (TokenPair, error) {
+	claims, err := json.Marshal(custom)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("encoding custom claims: %w", err)
+	}
+	return s.issuePair(ctx, RefreshRecord{
+		FamilyID: uuid.Must(uuid.NewV4()).String(),
+		UserID:   subject,
+		Claims:   claims,
+	})
+}
+
+// Refresh exchanges a refresh token for a new pair with the same claims.
+// Each refresh token can be exchanged once; presenting one again revokes
+// its whole family, so both the thief and the user have to sign in again.
+// With a revocation store, the access tokens issued with the family are
+// revoked too; the user's other sessions are left alone. Without one,
+// access tokens already issued stay valid until they expire.
+func (s Service) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	if s.refresh == nil {
+		return TokenPair{}, errors.New("no refresh store configured")
+	}
+
+	now := time.Now()
+	rec, err := s.refresh.Consume(ctx, hashRefreshToken(refreshToken), now)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("consuming refresh token: %w", err)
+	}
+
+	switch {
+	case !rec.RevokedAt.IsZero():
+		return TokenPair{}, fmt.Errorf("refresh token revoked: %w", ErrInvalidToken)
+	case !rec.UsedAt.IsZero():
+		if err := s.revokeFamily(ctx, rec.FamilyID, now); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrRefreshTokenReused
+	case !now.Before(rec.ExpiresAt):
+		return TokenPair{}, fmt.Errorf("refresh token: %w: %w", ErrTokenExpired, ErrInvalidToken)
+	}
+	if err := s.checkRevoked(ctx, "", rec.UserID, rec.CreatedAt); err != nil {
+		return TokenPair{}, err
+	}
+
+	return s.issuePair(ctx, RefreshRecord{
+		FamilyID: rec.FamilyID,
+		UserID:   rec.UserID,
+		Claims:   rec.Claims,
+	})
+}
+
+// revokeFamily revokes the refresh tokens of a family and, with a
+// revocation store, the access tokens issued with them.
+func (s Service) revokeFamily(ctx context.Context, familyID string, now time.Time) error {
+	revoked, err := s.refresh.RevokeFamily(ctx, familyID, now)
+	if err != nil {
+		return fmt.Errorf("revoking refresh token family [%s]: %w", familyID, err)
+	}
+	if s.revocations == nil {
+		return nil
+	}
+
+	for _, rec := range revoked {
+		if rec.AccessTokenID == "" {
+			continue
+		}
+		// The access token expires before its refresh token unless the
+		// expiries were configured the other way around
+		expiresAt := maxTime(rec.ExpiresAt, rec.CreatedAt.Add(s.expiry))
+		if err := s.revocations.Revoke(ctx, rec.AccessTokenID, expiresAt); err != nil {
+			return fmt.Errorf("revoking access token [%s]: %w", rec.AccessTokenID, err)
+		}
+	}
+	return nil
+}
+
+// RevokeRefreshToken signs out the session of refreshToken by revoking its
+// family and, with a revocation store, the access tokens issued with it.
+func (s Service) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	if s.refresh == nil {
+		return errors.New("no refresh store configured")
+	}
+
+	now := time.Now()
+	rec, err := s.refresh.Consume(ctx, hashRefreshToken(refreshToken), now)
+	if err != nil {
+		return fmt.Errorf("consuming refresh token: %w", err)
+	}
+	return s.revokeFamily(ctx, rec.FamilyID, now)
+}
+
+// issuePair signs an access token for rec and stores a new refresh token in
+// rec's family.
+func (s Service) issuePair(ctx context.Context, rec RefreshRecord) (TokenPair, error) {
+	if s.refresh == nil {
+		return TokenPair{}, errors.New("no refresh store configured")
+	}
+
+	access, claims, err := signToken(s, rec.UserID, rec.Claims)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return TokenPair{}, fmt.Errorf("generating refresh token: %w", err)
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	rec.ID = hashRefreshToken(refresh)
+	rec.AccessTokenID = claims.ID
+	rec.CreatedAt = now
+	rec.ExpiresAt = now.Add(s.refreshExpiry)
+	if err := s.refresh.Create(ctx, rec); err != nil {
+		return TokenPair{}, fmt.Errorf("storing refresh token: %w", err)
+	}
+
+	return TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		ExpiresAt:        now.Add(s.expiry),
+		RefreshExpiresAt: rec.ExpiresAt,
+	}, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryRefreshStore is a RefreshStore for a single instance and tests.
+type MemoryRefreshStore struct {
+	mu      sync.Mutex
+	records map[string]RefreshRecord
+}
+
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{records: make(map[string]RefreshRecord)}
+}
+
+func (m *MemoryRefreshStore) Create(_ context.Context, rec RefreshRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.records[rec.ID]; ok {
+		return fmt.Errorf("duplicate refresh token [%s]", rec.ID)
+	}
+	m.records[rec.ID] = rec
+	return nil
+}
+
+func (m *MemoryRefreshStore) Consume(_ context.Context, id string, now time.Time) (RefreshRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.records[id]
+	if !ok {
+		return RefreshRecord{}, fmt.Errorf("unknown refresh token: %w", ErrInvalidToken)
+	}
+	if rec.UsedAt.IsZero() {
+		used := rec
+		used.UsedAt = now
+		m.records[id] = used
+	}
+	return rec, nil
+}
+
+func (m *MemoryRefreshStore) RevokeFamily(_ context.Context, familyID string, now time.Time) ([]RefreshRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var revoked []RefreshRecord
+	for id, rec := range m.records {
+		if rec.FamilyID == familyID && rec.RevokedAt.IsZero() {
+			revoked = append(revoked, rec)
+			rec.RevokedAt = now
+			m.records[id] = rec
+		}
+	}
+	return revoked, nil
+}
+
+// Prune removes the tokens that expired before now and returns how many
+// were removed.
+func (m *MemoryRefreshStore) Prune(now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, rec := range m.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(m.records, id)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,223 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRefresh_Rotation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRefreshStore()
+	svc := NewService("test-secret", "test-issuer", "15m").WithRefreshStore(store)
+
+	first, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if first.RefreshToken == first.AccessToken {
+		t.Fatal("expected a separate refresh token")
+	}
+	if _, err := svc.ValidateToken(first.RefreshToken); err == nil {
+		t.Error("expected refresh token not to be accepted as an access token")
+	}
+	if _, err := svc.Refresh(ctx, first.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected access token not to refresh, got %v", err)
+	}
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("expected the refresh token to rotate")
+	}
+	claims, err := svc.ValidateToken(second.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate refreshed access token: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.AccountType != "user" {
+		t.Errorf("expected claims carried over, got %+v", claims)
+	}
+
+	third, err := svc.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh rotated token: %v", err)
+	}
+
+	// Replaying an exchanged token revokes the whole family
+	if _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected reuse to be detected, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected the latest token of the family to be revoked, got %v", err)
+	}
+
+	// Other sign-ins are unaffected
+	other, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("expected other families to keep working, got %v", err)
+	}
+}
+
+func TestRefresh_KeepsClaims(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "15m").WithRefreshStore(NewMemoryRefreshStore())
+
+	pair, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user", "orders:read")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	refreshed, err := svc.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	claims, err := svc.ValidateToken(refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate refreshed access token: %v", err)
+	}
+	if claims.Email != "test@example.com" || !slices.Equal(claims.Scopes(), []string{"orders:read"}) {
+		t.Errorf("expected email and scopes to survive the refresh, got %+v", claims)
+	}
+
+	type tenantClaims struct {
+		TenantID string   `json:"tenant_id"`
+		Roles    []string `json:"roles"`
+	}
+	pair, err = IssueTokens(ctx, svc, "user-2", tenantClaims{TenantID: "acme", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	refreshed, err = svc.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	custom, err := ValidateToken[tenantClaims](svc, refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate refreshed access token: %v", err)
+	}
+	if custom.Subject != "user-2" || custom.Custom.TenantID != "acme" || !slices.Equal(custom.Custom.Roles, []string{"admin"}) {
+		t.Errorf("expected custom claims to survive the refresh, got %+v", custom)
+	}
+}
+
+func TestRefresh_ReuseRevokesFamilyAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "15m").
+		WithRefreshStore(NewMemoryRefreshStore()).
+		WithRevocationStore(NewMemoryRevocationStore())
+
+	first, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	// A session of the same user on another device
+	other, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+
+	if _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected reuse to be detected, got %v", err)
+	}
+	for _, token := range []string{first.AccessToken, second.AccessToken} {
+		if _, err := svc.ValidateToken(token); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("expected access tokens of the family to be revoked, got %v", err)
+		}
+	}
+
+	if _, err := svc.ValidateToken(other.AccessToken); err != nil {
+		t.Errorf("expected the other session's access token to stay valid, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("expected the other session to keep refreshing, got %v", err)
+	}
+}
+
+func TestRefresh_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "15m").WithRefreshStore(NewMemoryRefreshStore())
+
+	pair, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Refresh(ctx, pair.RefreshToken)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly one exchange to succeed, got %d", succeeded)
+	}
+}
+
+func TestRefresh_Expired(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "15m").WithRefreshStore(NewMemoryRefreshStore())
+	svc.refreshExpiry = -time.Second
+
+	pair, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrTokenExpired) || !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrTokenExpired matching ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "15m").
+		WithRefreshStore(NewMemoryRefreshStore()).
+		WithRevocationStore(NewMemoryRevocationStore())
+
+	pair, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if err := svc.RevokeRefreshToken(ctx, pair.RefreshToken); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+	if _, err := svc.ValidateToken(pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected the session's access token to be revoked, got %v", err)
+	}
+}
+
+func TestLoadService_RefreshExpiry(t *testing.T) {
+	svc, err := LoadService(Config{SecretKey: "test-secret", Expiry: "15m", RefreshExpiry: "168h"})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	if svc.refreshExpiry != 7*24*time.Hour {
+		t.Errorf("expected refresh expiry 168h, got %v", svc.refreshExpiry)
+	}
+}
@@ -0,0 +1,358 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Defaults of VerifierConfig
+const (
+	defaultJWKSCacheTTL     = time.Hour
+	defaultJWKSMinRefresh   = time.Minute
+	defaultJWKSFetchTimeout = 10 * time.Second
+)
+
+// maxJWKSSize bounds the size of a fetched JWKS document.
+const maxJWKSSize = 1 << 20
+
+// Issuer is a trusted issuer of tokens verified with its published JWKS.
+type Issuer struct {
+	// Issuer must match the "iss" claim, e.g.
+	// "https://<project>.supabase.co/auth/v1".
+	Issuer string
+	// JWKSURL is where the issuer publishes its keys, e.g.
+	// "https://<project>.supabase.co/auth/v1/.well-known/jwks.json".
+	JWKSURL string
+	// Audience must be in the "aud" claim when set (Supabase uses
+	// "authenticated").
+	Audience string
+}
+
+// VerifierConfig configures a Verifier.
+type VerifierConfig struct {
+	Issuers []Issuer
+	// Fallback validates tokens from other issuers, such as the app's own
+	// Service. Without it, those tokens are rejected.
+	Fallback TokenValidator
+	// Client fetches JWKS documents; a client with a 10s timeout by
+	// default.
+	Client *http.Client
+	// CacheTTL is how long a JWKS document is used when its response has no
+	// max-age (1h by default).
+	CacheTTL time.Duration
+	// MinRefreshInterval is the minimum time between two fetches of the
+	// same JWKS, failed ones included, which bounds refetches for unknown
+	// key IDs and retries while the issuer is down (1m by default).
+	MinRefreshInterval time.Duration
+	// Leeway is the clock drift allowed with the issuers when checking
+	// "exp", "nbf" and "iat".
+	Leeway time.Duration
+}
+
+// Verifier validates tokens from remote issuers with their published keys,
+// and tokens from other issuers with a fallback validator. Claims are
+// mapped to a common view: UserID is "user_id" or else "sub", and
+// AccountType is "account_type" or else "role".
+type Verifier struct {
+	issuers  map[string]*remoteIssuer
+	fallback TokenValidator
+	leeway   time.Duration
+}
+
+type remoteIssuer struct {
+	Issuer
+	keys *jwksCache
+}
+
+// NewVerifier returns a verifier for the issuers in cfg. Keys are fetched
+// when first needed.
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultJWKSFetchTimeout}
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultJWKSCacheTTL
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = defaultJWKSMinRefresh
+	}
+
+	v := &Verifier{issuers: make(map[string]*remoteIssuer), fallback: cfg.Fallback, leeway: cfg.Leeway}
+	for _, iss := range cfg.Issuers {
+		if iss.Issuer == "" || iss.JWKSURL == "" {
+			return nil, errors.New("issuer and jwks url are required")
+		}
+		if _, ok := v.issuers[iss.Issuer]; ok {
+			return nil, fmt.Errorf("duplicate issuer [%s]", iss.Issuer)
+		}
+		v.issuers[iss.Issuer] = &remoteIssuer{
+			Issuer: iss,
+			keys: &jwksCache{
+				url:        iss.JWKSURL,
+				client:     cfg.Client,
+				ttl:        cfg.CacheTTL,
+				minRefresh: cfg.MinRefreshInterval,
+				now:        time.Now,
+			},
+		}
+	}
+	return v, nil
+}
+
+// remoteClaims are the claims read from remote tokens.
+type remoteClaims struct {
+	UserID      string `json:"user_id"`
+	Email       string `json:"email"`
+	AccountType string `json:"account_type"`
+	Role        string `json:"role"`
+	Scope       string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// ValidateToken validates token against the trusted issuer named in its
+// "iss" claim, or with the fallback validator for other issuers.
+func (v *Verifier) ValidateToken(token string) (*Claims, error) {
+	return v.ValidateTokenContext(context.Background(), token)
+}
+
+// ValidateTokenContext is ValidateToken with a context bounding the fetch of
+// the issuer's keys, such as the context of the request being
+// authenticated.
+func (v *Verifier) ValidateTokenContext(ctx context.Context, token string) (*Claims, error) {
+	var unverified jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &unverified); err != nil {
+		return nil, parseError(err)
+	}
+
+	iss, ok := v.issuers[unverified.Issuer]
+	if !ok {
+		if v.fallback != nil {
+			return validateToken(ctx, v.fallback, token)
+		}
+		return nil, fmt.Errorf("untrusted issuer [%s]: %w: %w", unverified.Issuer, ErrInvalidIssuer, ErrInvalidToken)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithIssuer(iss.Issuer.Issuer),
+		jwt.WithValidMethods([]string{AlgRS256, AlgES256, AlgEdDSA}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.leeway),
+	}
+	if iss.Audience != "" {
+		opts = append(opts, jwt.WithAudience(iss.Audience))
+	}
+
+	parsed, err := jwt.ParseWithClaims(token, &remoteClaims{}, func(t *jwt.Token) (interface{}, error) {
+		return iss.verificationKey(ctx, t)
+	}, opts...)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	rc, ok := parsed.Claims.(*remoteClaims)
+	if !ok || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{
+		UserID:           rc.UserID,
+		Email:            rc.Email,
+		AccountType:      rc.AccountType,
+		Scope:            rc.Scope,
+		RegisteredClaims: rc.RegisteredClaims,
+	}
+	if claims.UserID == "" {
+		claims.UserID = rc.Subject
+	}
+	if claims.AccountType == "" {
+		claims.AccountType = rc.Role
+	}
+	return claims, nil
+}
+
+func (iss *remoteIssuer) verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := iss.keys.key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// jwksCache holds the keys of a remote JWKS document. The document is
+// refreshed when its max-age passes, revalidated with its ETag, and
+// refetched early for unknown key IDs. Fetches, failed ones included, are
+// at least minRefresh apart, and known keys keep being served while a fetch
+// is in progress or after it failed.
+type jwksCache struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]Key
+	etag      string
+	expires   time.Time
+	lastFetch time.Time
+	lastErr   error
+	// fetching is closed when the fetch in progress completes; lookups of
+	// unknown key IDs wait for it instead of fetching again
+	fetching chan struct{}
+}
+
+// jwksDocument is the result of a fetch. notModified is set on a 304, in
+// which case keys and etag are empty.
+type jwksDocument struct {
+	keys        map[string]Key
+	etag        string
+	maxAge      time.Duration
+	notModified bool
+}
+
+func (c *jwksCache) key(ctx context.Context, kid string) (Key, error) {
+	for {
+		c.mu.Lock()
+		now := c.now()
+		key, known := c.keys[kid]
+		if known && now.Before(c.expires) {
+			c.mu.Unlock()
+			return key, nil
+		}
+
+		if wait := c.fetching; wait != nil {
+			c.mu.Unlock()
+			if known {
+				return key, nil
+			}
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return Key{}, fmt.Errorf("fetching jwks [%s]: %w", c.url, ctx.Err())
+			}
+		}
+
+		if !c.lastFetch.IsZero() && now.Sub(c.lastFetch) < c.minRefresh {
+			err := c.lastErr
+			c.mu.Unlock()
+			switch {
+			case known:
+				return key, nil
+			case err != nil:
+				return Key{}, err
+			}
+			return Key{}, fmt.Errorf("unknown key id [%s]", kid)
+		}
+
+		wait := make(chan struct{})
+		previous := c.lastFetch
+		c.fetching = wait
+		c.lastFetch = now
+		etag := ""
+		if c.keys != nil {
+			etag = c.etag
+		}
+		c.mu.Unlock()
+
+		doc, err := c.fetch(ctx, etag)
+
+		c.mu.Lock()
+		switch {
+		case err == nil:
+			if !doc.notModified {
+				c.keys = doc.keys
+				c.etag = doc.etag
+			}
+			c.expires = now.Add(doc.maxAge)
+			c.lastErr = nil
+		case ctx.Err() != nil:
+			// The caller gave up; that says nothing about the issuer
+			c.lastFetch = previous
+		default:
+			c.lastErr = err
+		}
+		c.fetching = nil
+		close(wait)
+		c.mu.Unlock()
+
+		if err != nil && ctx.Err() != nil {
+			if known {
+				return key, nil
+			}
+			return Key{}, err
+		}
+	}
+}
+
+// fetch requests the JWKS document, conditionally when etag is set.
+func (c *jwksCache) fetch(ctx context.Context, etag string) (jwksDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return jwksDocument{}, fmt.Errorf("fetching jwks [%s]: %w", c.url, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return jwksDocument{}, fmt.Errorf("fetching jwks [%s]: %w", c.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return jwksDocument{maxAge: c.maxAge(resp.Header), notModified: true}, nil
+	case http.StatusOK:
+	default:
+		return jwksDocument{}, fmt.Errorf("fetching jwks [%s]: unexpected status %d", c.url, resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return jwksDocument{}, fmt.Errorf("decoding jwks [%s]: %w", c.url, err)
+	}
+
+	keys := make(map[string]Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, not fatal
+		if key, err := jwk.Key(); err == nil {
+			keys[key.ID] = key
+		}
+	}
+
+	return jwksDocument{keys: keys, etag: resp.Header.Get("ETag"), maxAge: c.maxAge(resp.Header)}, nil
+}
+
+// maxAge returns how long a response may be cached: its Cache-Control
+// max-age, or the default TTL, and never less than minRefresh.
+func (c *jwksCache) maxAge(h http.Header) time.Duration {
+	ttl := c.ttl
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(directive), "max-age="); ok {
+			if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return max(ttl, c.minRefresh)
+}
@@ -0,0 +1,363 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer serves the JWKS of keys and counts full and revalidated
+// fetches.
+func jwksServer(t *testing.T, keys *Keyring) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var fetches, revalidations atomic.Int32
+	h := JWKSHandler(keys)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.Header.Get("If-None-Match") != "" {
+			revalidations.Add(1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &fetches, &revalidations
+}
+
+func signRemoteToken(t *testing.T, key Key, claims jwt.MapClaims) string {
+	t.Helper()
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		t.Fatalf("failed to get signing method: %v", err)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func supabaseClaims(iss, aud string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   iss,
+		"aud":   aud,
+		"sub":   "user-1",
+		"email": "test@example.com",
+		"role":  "authenticated",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	key, _ := GenerateKey(AlgES256)
+	keys, err := NewKeyring(key)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	srv, _, _ := jwksServer(t, keys)
+
+	const issuer = "https://project.supabase.co/auth/v1"
+	v, err := NewVerifier(VerifierConfig{
+		Issuers: []Issuer{{Issuer: issuer, JWKSURL: srv.URL, Audience: "authenticated"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	claims, err := v.ValidateToken(signRemoteToken(t, key, supabaseClaims(issuer, "authenticated")))
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.Email != "test@example.com" || claims.AccountType != "authenticated" {
+		t.Errorf("expected claims mapped from sub and role, got %+v", claims)
+	}
+
+	other, _ := GenerateKey(AlgES256)
+	expired := supabaseClaims(issuer, "authenticated")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	noExpiry := supabaseClaims(issuer, "authenticated")
+	delete(noExpiry, "exp")
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "wrong audience", token: signRemoteToken(t, key, supabaseClaims(issuer, "anon")), want: ErrInvalidToken},
+		{name: "untrusted issuer", token: signRemoteToken(t, key, supabaseClaims("https://evil.example.com", "authenticated")), want: ErrInvalidToken},
+		{name: "unknown key", token: signRemoteToken(t, other, supabaseClaims(issuer, "authenticated")), want: ErrInvalidToken},
+		{name: "expired", token: signRemoteToken(t, key, expired), want: ErrTokenExpired},
+		{name: "no expiry", token: signRemoteToken(t, key, noExpiry), want: ErrInvalidToken},
+		{name: "malformed", token: "not-a-token", want: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.ValidateToken(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_Refresh(t *testing.T) {
+	first, _ := GenerateKey(AlgRS256)
+	keys, err := NewKeyring(first)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	srv, fetches, revalidations := jwksServer(t, keys)
+
+	const issuer = "https://auth.example.com"
+	v, err := NewVerifier(VerifierConfig{
+		Issuers:            []Issuer{{Issuer: issuer, JWKSURL: srv.URL}},
+		MinRefreshInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	now := time.Now()
+	cache := v.issuers[issuer].keys
+	cache.now = func() time.Time { return now }
+
+	validate := func(key Key) error {
+		_, err := v.ValidateToken(signRemoteToken(t, key, supabaseClaims(issuer, "")))
+		return err
+	}
+
+	if err := validate(first); err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if err := validate(first); err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected the jwks to be cached, got %d fetches", n)
+	}
+
+	// The issuer rotates; its new key is unknown until the next fetch, which
+	// is rate limited
+	second, _ := GenerateKey(AlgRS256)
+	if err := keys.Add(second); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+	if err := validate(second); err == nil {
+		t.Error("expected unknown key to be rejected within the refresh interval")
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected refetch to be rate limited, got %d fetches", n)
+	}
+
+	now = now.Add(time.Minute)
+	if err := validate(second); err != nil {
+		t.Errorf("expected unknown key to be refetched, got %v", err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("expected 2 fetches, got %d", n)
+	}
+
+	// After max-age the document is revalidated with its ETag
+	now = now.Add(5 * time.Minute)
+	before := revalidations.Load()
+	if err := validate(first); err != nil {
+		t.Errorf("failed to validate token after revalidation: %v", err)
+	}
+	if n := revalidations.Load() - before; n != 1 {
+		t.Errorf("expected 1 conditional fetch, got %d", n)
+	}
+	if !cache.expires.After(now) {
+		t.Error("expected a 304 to extend the cache")
+	}
+}
+
+func TestVerifier_Fallback(t *testing.T) {
+	remote, _ := GenerateKey(AlgEdDSA)
+	keys, err := NewKeyring(remote)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	srv, fetches, _ := jwksServer(t, keys)
+
+	local := NewService("secret", "app", "1h")
+	v, err := NewVerifier(VerifierConfig{
+		Issuers:  []Issuer{{Issuer: "https://auth.example.com", JWKSURL: srv.URL}},
+		Fallback: local,
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	token, err := local.GenerateToken("user-2", "local@example.com", "admin")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	claims, err := v.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("expected fallback to validate local token: %v", err)
+	}
+	if claims.UserID != "user-2" || claims.AccountType != "admin" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if n := fetches.Load(); n != 0 {
+		t.Errorf("expected no jwks fetch for local tokens, got %d", n)
+	}
+}
+
+func TestNewVerifier_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuers []Issuer
+	}{
+		{name: "missing url", issuers: []Issuer{{Issuer: "https://a.example.com"}}},
+		{name: "duplicate", issuers: []Issuer{
+			{Issuer: "https://a.example.com", JWKSURL: "https://a.example.com/jwks"},
+			{Issuer: "https://a.example.com", JWKSURL: "https://b.example.com/jwks"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewVerifier(VerifierConfig{Issuers: tt.issuers}); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestVerifier_FailedFetchBackoff(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	const issuer = "https://auth.example.com"
+	v, err := NewVerifier(VerifierConfig{Issuers: []Issuer{{Issuer: issuer, JWKSURL: srv.URL}}})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	now := time.Now()
+	v.issuers[issuer].keys.now = func() time.Time { return now }
+
+	key, _ := GenerateKey(AlgES256)
+	token := signRemoteToken(t, key, supabaseClaims(issuer, ""))
+	for range 3 {
+		if _, err := v.ValidateToken(token); err == nil {
+			t.Fatal("expected validation to fail while the jwks is unavailable")
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected failed fetches to be rate limited, got %d fetches", n)
+	}
+
+	now = now.Add(time.Minute)
+	_, _ = v.ValidateToken(token)
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("expected a retry after the refresh interval, got %d fetches", n)
+	}
+}
+
+func TestVerifier_ServesCachedKeysDuringRefresh(t *testing.T) {
+	key, _ := GenerateKey(AlgES256)
+	keys, err := NewKeyring(key)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+
+	var blocking atomic.Bool
+	fetching, release := make(chan struct{}), make(chan struct{})
+	h := JWKSHandler(keys)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if blocking.Load() {
+			close(fetching)
+			<-release
+		}
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	const issuer = "https://auth.example.com"
+	v, err := NewVerifier(VerifierConfig{Issuers: []Issuer{{Issuer: issuer, JWKSURL: srv.URL}}})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	var now atomic.Pointer[time.Time]
+	start := time.Now()
+	now.Store(&start)
+	v.issuers[issuer].keys.now = func() time.Time { return *now.Load() }
+
+	token := signRemoteToken(t, key, supabaseClaims(issuer, ""))
+	if _, err := v.ValidateToken(token); err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+
+	// Past max-age, one request refreshes while the others use the cached keys
+	later := start.Add(2 * time.Hour)
+	now.Store(&later)
+	blocking.Store(true)
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := v.ValidateToken(token)
+		refreshed <- err
+	}()
+	<-fetching
+
+	if _, err := v.ValidateToken(token); err != nil {
+		t.Errorf("expected cached keys during the refresh, got %v", err)
+	}
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Errorf("failed to validate token while refreshing: %v", err)
+	}
+}
+
+func TestVerifier_ValidateTokenContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	const issuer = "https://auth.example.com"
+	v, err := NewVerifier(VerifierConfig{Issuers: []Issuer{{Issuer: issuer, JWKSURL: srv.URL}}})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	key, _ := GenerateKey(AlgES256)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := v.ValidateTokenContext(ctx, signRemoteToken(t, key, supabaseClaims(issuer, ""))); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the fetch to stop with the context, got %v", err)
+	}
+
+	// A cancelled caller doesn't hold back the next fetch
+	if cache := v.issuers[issuer].keys; !cache.lastFetch.IsZero() {
+		t.Errorf("expected no backoff after a cancelled fetch, got last fetch %v", cache.lastFetch)
+	}
+}
+
+func TestJWK_RejectsSmallRSAKeys(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	jwk := JWK{
+		KeyType: "RSA",
+		KeyID:   "small",
+		N:       base64.RawURLEncoding.EncodeToString(priv.N.Bytes()),
+		E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(priv.E)).Bytes()),
+	}
+	if _, err := jwk.Key(); err == nil {
+		t.Error("expected a 1024-bit rsa key to be rejected")
+	}
+}
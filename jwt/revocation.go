@@ -0,0 +1,192 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// revocationPruneInterval is how often MemoryRevocationStore drops expired
+// entries as it is written to.
+const revocationPruneInterval = time.Minute
+
+// RevocationStore keeps revoked tokens until they would have expired
+// anyway. MemoryRevocationStore keeps them in memory; the pgstore package
+// keeps them in Postgres.
+type RevocationStore interface {
+	// Revoke revokes the token with jti. The entry can be dropped after
+	// expiresAt.
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeSubject revokes the tokens of subject issued before before.
+	// The entry can be dropped after expiresAt. Later calls only move
+	// before forward.
+	RevokeSubject(ctx context.Context, subject string, before, expiresAt time.Time) error
+	// IsRevoked reports whether the token with jti, or every token of
+	// subject issued at issuedAt, is revoked.
+	IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error)
+}
+
+// WithRevocationStore returns a copy of the service that rejects tokens
+// revoked in store.
+func (s Service) WithRevocationStore(store RevocationStore) Service {
+	s.revocations = store
+	return s
+}
+
+// RevokeToken revokes a valid token before its expiry, e.g. on sign out.
+func (s Service) RevokeToken(ctx context.Context, tokenString string) error {
+	if s.revocations == nil {
+		return errNoRevocationStore
+	}
+
+	claims, err := s.ValidateTokenContext(ctx, tokenString)
+	if err != nil {
+		return fmt.Errorf("invalid token for revocation: %w", err)
+	}
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return fmt.Errorf("token without jti or exp can't be revoked: %w", ErrInvalidToken)
+	}
+
+	if err := s.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return fmt.Errorf("revoking token [%s]: %w", claims.ID, err)
+	}
+	return nil
+}
+
+// RevokeSubject revokes every access and refresh token of subject issued
+// before before, e.g. to sign a user out everywhere or after a password
+// change; tokens issued from before on, such as the fresh pair of the
+// password change, stay valid. Access tokens are dated by their "iat" and
+// "iat_ns" claims, so tokens without "iat_ns" issued in the same second as
+// before are revoked too.
+func (s Service) RevokeSubject(ctx context.Context, subject string, before time.Time) error {
+	if s.revocations == nil {
+		return errNoRevocationStore
+	}
+
+	expiresAt := before.Add(max(s.expiry, s.refreshExpiry))
+	if err := s.revocations.RevokeSubject(ctx, subject, before, expiresAt); err != nil {
+		return fmt.Errorf("revoking tokens of subject [%s]: %w", subject, err)
+	}
+	return nil
+}
+
+// checkRevoked returns ErrTokenRevoked if the token with jti, issued to
+// subject at issuedAt, is revoked.
+func (s Service) checkRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) error {
+	if s.revocations == nil {
+		return nil
+	}
+
+	revoked, err := s.revocations.IsRevoked(ctx, jti, subject, issuedAt)
+	if err != nil {
+		return fmt.Errorf("checking token revocation: %w: %w", ErrInvalidToken, err)
+	}
+	if revoked {
+		return fmt.Errorf("%w: %w", ErrTokenRevoked, ErrInvalidToken)
+	}
+	return nil
+}
+
+var errNoRevocationStore = errors.New("no revocation store configured")
+
+// MemoryRevocationStore is a RevocationStore for a single instance and
+// tests. Expired entries are dropped as new ones are written.
+type MemoryRevocationStore struct {
+	mu        sync.Mutex
+	tokens    map[string]time.Time
+	subjects  map[string]subjectRevocation
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type subjectRevocation struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+		now:      time.Now,
+	}
+}
+
+func (m *MemoryRevocationStore) Revoke(_ context.Context, jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[jti] = expiresAt
+	m.maybePrune()
+	return nil
+}
+
+func (m *MemoryRevocationStore) RevokeSubject(_ context.Context, subject string, before, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rev := m.subjects[subject]
+	rev.before = maxTime(rev.before, before)
+	rev.expiresAt = maxTime(rev.expiresAt, expiresAt)
+	m.subjects[subject] = rev
+	m.maybePrune()
+	return nil
+}
+
+func (m *MemoryRevocationStore) IsRevoked(_ context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tokens[jti]; ok && jti != "" {
+		return true, nil
+	}
+	if rev, ok := m.subjects[subject]; ok && subject != "" && issuedAt.Before(rev.before) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// Prune drops the entries that expired before now and returns how many
+// were dropped.
+func (m *MemoryRevocationStore) Prune(now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.prune(now)
+}
+
+func (m *MemoryRevocationStore) maybePrune() {
+	now := m.now()
+	if now.Sub(m.lastPrune) >= revocationPruneInterval {
+		m.prune(now)
+	}
+}
+
+func (m *MemoryRevocationStore) prune(now time.Time) int {
+	m.lastPrune = now
+
+	removed := 0
+	for jti, expiresAt := range m.tokens {
+		if !now.Before(expiresAt) {
+			delete(m.tokens, jti)
+			removed++
+		}
+	}
+	for subject, rev := range m.subjects {
+		if !now.Before(rev.expiresAt) {
+			delete(m.subjects, subject)
+			removed++
+		}
+	}
+	return removed
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
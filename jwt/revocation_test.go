@@ -0,0 +1,208 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "1h").WithRevocationStore(NewMemoryRevocationStore())
+
+	token, _ := svc.GenerateToken("user-1", "test@example.com", "user")
+	other, _ := svc.GenerateToken("user-1", "test@example.com", "user")
+
+	if err := svc.RevokeToken(ctx, token); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if _, err := svc.ValidateToken(token); !errors.Is(err, ErrTokenRevoked) || !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+	if _, err := svc.ValidateToken(other); err != nil {
+		t.Errorf("expected other tokens of the user to stay valid, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	Authenticate(svc, AuthConfig{Realm: "api"})(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("WWW-Authenticate"), "token has been revoked") {
+		t.Errorf("expected 401 revoked challenge, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestRevokeSubject(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "1h").
+		WithRefreshStore(NewMemoryRefreshStore()).
+		WithRevocationStore(NewMemoryRevocationStore())
+
+	old, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	otherUser, _ := svc.GenerateToken("user-2", "other@example.com", "user")
+
+	if err := svc.RevokeSubject(ctx, "user-1", time.Now()); err != nil {
+		t.Fatalf("failed to revoke subject: %v", err)
+	}
+
+	if _, err := svc.ValidateToken(old.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected access tokens issued before the cutoff to be revoked, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, old.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected refresh tokens issued before the cutoff to be revoked, got %v", err)
+	}
+	if _, err := svc.ValidateToken(otherUser); err != nil {
+		t.Errorf("expected other subjects to stay valid, got %v", err)
+	}
+
+	// A fresh pair issued right after the cutoff, as on a password change,
+	// stays valid even within the same second
+	fresh, err := svc.IssueTokens(ctx, "user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if _, err := svc.ValidateToken(fresh.AccessToken); err != nil {
+		t.Errorf("expected an access token issued after the cutoff to stay valid, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, fresh.RefreshToken); err != nil {
+		t.Errorf("expected a refresh token issued after the cutoff to stay valid, got %v", err)
+	}
+}
+
+func TestRevokeSubject_SubSecond(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService("test-secret", "test-issuer", "1h").WithRevocationStore(NewMemoryRevocationStore())
+
+	token, _ := svc.GenerateToken("user-1", "test@example.com", "user")
+	claims, err := ValidateToken[userClaims](svc, token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	issuedAt := claims.issuedAt()
+	if issuedAt.Nanosecond() != int(claims.IssuedAtNanos) {
+		t.Fatalf("expected iat_ns to carry the sub-second issue time, got %d", claims.IssuedAtNanos)
+	}
+
+	tests := []struct {
+		name    string
+		before  time.Time
+		revoked bool
+	}{
+		{name: "cutoff at issue time", before: issuedAt, revoked: false},
+		{name: "cutoff just after issue time", before: issuedAt.Add(time.Nanosecond), revoked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := svc.RevokeSubject(ctx, "user-1", tt.before); err != nil {
+				t.Fatalf("failed to revoke subject: %v", err)
+			}
+			_, err := svc.ValidateToken(token)
+			if revoked := errors.Is(err, ErrTokenRevoked); revoked != tt.revoked {
+				t.Errorf("expected revoked=%v, got %v", tt.revoked, err)
+			}
+		})
+	}
+}
+
+func TestTokenClaims_IssuedAtNanos(t *testing.T) {
+	iat := jwt.NewNumericDate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	claims := TokenClaims[userClaims]{RegisteredClaims: jwt.RegisteredClaims{IssuedAt: iat}, IssuedAtNanos: 250}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("failed to encode claims: %v", err)
+	}
+	var decoded TokenClaims[userClaims]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode claims: %v", err)
+	}
+	if !decoded.issuedAt().Equal(iat.Add(250)) {
+		t.Errorf("expected issue time %v, got %v", iat.Add(250), decoded.issuedAt())
+	}
+
+	// Tokens without iat_ns date from the start of their iat second
+	var foreign TokenClaims[userClaims]
+	if err := json.Unmarshal([]byte(`{"iat":1735689600}`), &foreign); err != nil {
+		t.Fatalf("failed to decode claims: %v", err)
+	}
+	if !foreign.issuedAt().Equal(iat.Time) {
+		t.Errorf("expected issue time %v, got %v", iat.Time, foreign.issuedAt())
+	}
+
+	if err := json.Unmarshal([]byte(`{"iat":1735689600,"iat_ns":1000000000}`), &foreign); err == nil {
+		t.Error("expected error for an iat_ns of a second or more")
+	}
+}
+
+// contextRevocationStore records the context of revocation checks.
+type contextRevocationStore struct {
+	*MemoryRevocationStore
+	checked context.Context
+}
+
+func (s *contextRevocationStore) IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error) {
+	s.checked = ctx
+	return s.MemoryRevocationStore.IsRevoked(ctx, jti, subject, issuedAt)
+}
+
+func TestAuthenticate_RequestContext(t *testing.T) {
+	store := &contextRevocationStore{MemoryRevocationStore: NewMemoryRevocationStore()}
+	svc := NewService("test-secret", "test-issuer", "1h").WithRevocationStore(store)
+	token, _ := svc.GenerateToken("user-1", "test@example.com", "user")
+
+	type key struct{}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), key{}, "request"))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	Authenticate(svc, AuthConfig{Realm: "api"})(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the request to be authenticated, got %d", rec.Code)
+	}
+	if store.checked == nil || store.checked.Value(key{}) != "request" {
+		t.Error("expected the revocation check to use the request context")
+	}
+}
+
+func TestMemoryRevocationStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryRevocationStore()
+	store.now = func() time.Time { return now }
+
+	_ = store.Revoke(ctx, "a", now.Add(time.Hour))
+	_ = store.Revoke(ctx, "b", now.Add(3*time.Hour))
+	_ = store.RevokeSubject(ctx, "user-1", now, now.Add(2*time.Hour))
+	// Later cutoffs move forward, earlier ones are ignored
+	_ = store.RevokeSubject(ctx, "user-1", now.Add(-time.Hour), now.Add(time.Hour))
+
+	if revoked, _ := store.IsRevoked(ctx, "", "user-1", now.Add(-time.Minute)); !revoked {
+		t.Error("expected the latest cutoff to be kept")
+	}
+
+	// Writes prune expired entries once per interval
+	now = now.Add(150 * time.Minute)
+	_ = store.Revoke(ctx, "c", now.Add(time.Hour))
+
+	if revoked, _ := store.IsRevoked(ctx, "a", "", now); revoked {
+		t.Error("expected expired token entry to be pruned")
+	}
+	if revoked, _ := store.IsRevoked(ctx, "", "user-1", time.Time{}); revoked {
+		t.Error("expected expired subject entry to be pruned")
+	}
+	if revoked, _ := store.IsRevoked(ctx, "b", "", now); !revoked {
+		t.Error("expected unexpired entry to be kept")
+	}
+}
@@ -1,85 +1,318 @@
 package jwt
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/gofrs/uuid/v5"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Error is a token validation error.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// StatusCode reports validation errors as 401 Unauthorized and failed
+// requirements as 403 Forbidden when they reach an HTTP boundary.
+func (e Error) StatusCode() int {
+	if e == ErrInsufficientScope {
+		return 403
+	}
+	return 401
+}
+
+// Detail returns the message, which is safe to show to clients.
+func (e Error) Detail() string { return string(e) }
+
+// Validation errors, ErrTokenExpired included, also match ErrInvalidToken,
+// so callers that don't need the reason can check for it alone.
+const (
+	ErrInvalidToken      Error = "invalid token"
+	ErrTokenExpired      Error = "token has expired"
+	ErrInsufficientScope Error = "insufficient scope"
+	// ErrRefreshTokenReused is returned when a refresh token is exchanged
+	// twice. Its family is revoked.
+	ErrRefreshTokenReused Error = "refresh token reused"
+	ErrTokenRevoked       Error = "token has been revoked"
+	ErrTokenNotValidYet   Error = "token is not valid yet"
+	ErrInvalidAudience    Error = "token has an invalid audience"
+	ErrInvalidIssuer      Error = "token has an invalid issuer"
+	ErrInvalidSignature   Error = "token signature is invalid"
+)
+
 type Claims struct {
 	UserID      string `json:"user_id"`
 	Email       string `json:"email"`
 	AccountType string `json:"account_type"`
+	// Scope is a space-separated list of scopes, as in OAuth 2.0.
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Scopes returns the scopes of the "scope" claim.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// Service issues and validates tokens with the keys of its Keyring.
 type Service struct {
-	secretKey []byte
-	issuer    string
-	expiry    time.Duration
+	keys   *Keyring
+	issuer string
+	expiry time.Duration
+
+	refresh       RefreshStore
+	refreshExpiry time.Duration
+	revocations   RevocationStore
+	validation    ValidationOptions
 }
 
+// ValidationOptions are the checks of a Service beyond the signature,
+// expiry and issuer of tokens.
+type ValidationOptions struct {
+	// Audience is set as the "aud" claim of issued tokens; tokens are only
+	// accepted if their "aud" claim has one of its values. Empty accepts
+	// tokens without checking "aud".
+	Audience []string
+	// Leeway is the clock drift allowed between hosts when checking "exp",
+	// "nbf" and "iat".
+	Leeway time.Duration
+	// MaxAge rejects tokens issued longer ago, whatever their "exp". Zero
+	// means no limit.
+	MaxAge time.Duration
+}
+
+// WithValidation returns a copy of the service checking tokens with opts.
+func (s Service) WithValidation(opts ValidationOptions) Service {
+	s.validation = opts
+	return s
+}
+
+// NewService returns an HS256 service signing with secretKey.
 func NewService(secretKey, issuer string, expiry string) Service {
+	keys := &Keyring{now: time.Now}
+	keys.keys = []Key{{Algorithm: AlgHS256, PrivateKey: []byte(secretKey), PublicKey: []byte(secretKey)}}
+	return NewServiceWithKeyring(keys, issuer, expiry)
+}
+
+// NewServiceWithKeyring returns a service signing with the current key of
+// keys and verifying with any of its unexpired keys.
+func NewServiceWithKeyring(keys *Keyring, issuer string, expiry string) Service {
 	d, err := time.ParseDuration(expiry)
 	if err != nil {
 		d = 24 * time.Hour
 	}
 	return Service{
-		secretKey: []byte(secretKey),
-		issuer:    issuer,
-		expiry:    d,
+		keys:          keys,
+		issuer:        issuer,
+		expiry:        d,
+		refreshExpiry: defaultRefreshExpiry,
 	}
 }
 
+// NewServiceFromConfig returns a service using the algorithm and key in
+// cfg. Config errors are logged to slog.Default instead of returned: an
+// invalid duration keeps its default, an HS256 secret key is used as is,
+// even empty, as it always was, and any other key that fails to load
+// leaves the service without keys, so it can neither sign nor verify
+// tokens. Use LoadService to handle config errors instead.
 func NewServiceFromConfig(cfg Config) Service {
-	return NewService(cfg.SecretKey, cfg.Issuer, cfg.Expiry)
+	keys, err := loadKeyring(cfg)
+	if err != nil {
+		keys = &Keyring{now: time.Now}
+		if cfg.Algorithm == "" || cfg.Algorithm == AlgHS256 {
+			slog.Warn("invalid jwt key config, using the secret key as is", slog.String("error", err.Error()))
+			keys.keys = []Key{{ID: cfg.KeyID, Algorithm: AlgHS256, PrivateKey: []byte(cfg.SecretKey), PublicKey: []byte(cfg.SecretKey)}}
+		} else {
+			slog.Error("invalid jwt key config, the service has no keys", slog.String("error", err.Error()))
+		}
+	}
+
+	svc, err := configureService(keys, cfg)
+	if err != nil {
+		slog.Error("invalid jwt config, keeping the defaults", slog.String("error", err.Error()))
+	}
+	return svc
+}
+
+// LoadService returns a service using the algorithm and key in cfg, or an
+// error when the key or a duration in cfg is invalid.
+func LoadService(cfg Config) (Service, error) {
+	keys, err := loadKeyring(cfg)
+	if err != nil {
+		return Service{}, err
+	}
+
+	svc, err := configureService(keys, cfg)
+	if err != nil {
+		return Service{}, err
+	}
+	return svc, nil
 }
 
+func loadKeyring(cfg Config) (*Keyring, error) {
+	key, err := LoadKey(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("loading jwt key: %w", err)
+	}
+
+	keys, err := NewKeyring(key)
+	if err != nil {
+		return nil, fmt.Errorf("loading jwt key: %w", err)
+	}
+	return keys, nil
+}
+
+// configureService returns a service for keys with the expiries and
+// validation options of cfg. It returns every duration of cfg that fails
+// to parse, along with a service using the defaults for them.
+func configureService(keys *Keyring, cfg Config) (Service, error) {
+	var errs []error
+	parse := func(name, value string, d *time.Duration) {
+		if value == "" {
+			return
+		}
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("parsing jwt %s [%s]: %w", name, value, err))
+			return
+		}
+		*d = v
+	}
+
+	svc := NewServiceWithKeyring(keys, cfg.Issuer, "")
+	parse("expiry", cfg.Expiry, &svc.expiry)
+	parse("refresh expiry", cfg.RefreshExpiry, &svc.refreshExpiry)
+
+	opts := ValidationOptions{Audience: cfg.Audience}
+	parse("leeway", cfg.Leeway, &opts.Leeway)
+	parse("max age", cfg.MaxAge, &opts.MaxAge)
+
+	return svc.WithValidation(opts), errors.Join(errs...)
+}
+
+// Keyring returns the keys of the service, for rotation and for serving
+// them with JWKSHandler.
+func (s Service) Keyring() *Keyring {
+	return s.keys
+}
+
+// GenerateToken signs a token for a user. Use the GenerateToken function
+// for tokens with other claims.
 func (s Service) GenerateToken(userID, email, accountType string) (string, error) {
-	claims := &Claims{
+	return s.GenerateScopedToken(userID, email, accountType)
+}
+
+// GenerateScopedToken signs a token for a user granted scopes, for routes
+// guarded with RequireScopes.
+func (s Service) GenerateScopedToken(userID, email, accountType string, scopes ...string) (string, error) {
+	return GenerateToken(s, userID, userClaims{
 		UserID:      userID,
 		Email:       email,
 		AccountType: accountType,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.expiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    s.issuer,
-			Subject:   userID,
-			ID:        uuid.Must(uuid.NewV4()).String(),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secretKey)
+		Scope:       strings.Join(scopes, " "),
+	})
 }
 
+// ValidateToken validates a token of GenerateToken. Use the ValidateToken
+// function for tokens with other claims.
 func (s Service) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.secretKey, nil
-	})
+	return s.ValidateTokenContext(context.Background(), tokenString)
+}
 
+// ValidateTokenContext is ValidateToken with a context for the revocation
+// check, such as the context of the request being authenticated.
+func (s Service) ValidateTokenContext(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := ValidateTokenContext[userClaims](ctx, s, tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+		return nil, err
 	}
 
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+	return &Claims{
+		UserID:           claims.Custom.UserID,
+		Email:            claims.Custom.Email,
+		AccountType:      claims.Custom.AccountType,
+		Scope:            claims.Custom.Scope,
+		RegisteredClaims: claims.RegisteredClaims,
+	}, nil
+}
+
+// parseError wraps a parser error with the validation error of its
+// reason. A token failing several checks reports the first of signature,
+// expiry, not before, audience and issuer.
+func parseError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return fmt.Errorf("failed to parse token: %w: %w: %w", ErrInvalidSignature, ErrInvalidToken, err)
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return fmt.Errorf("failed to parse token: %w: %w: %w", ErrTokenExpired, ErrInvalidToken, err)
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return fmt.Errorf("failed to parse token: %w: %w: %w", ErrTokenNotValidYet, ErrInvalidToken, err)
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return fmt.Errorf("failed to parse token: %w: %w: %w", ErrInvalidAudience, ErrInvalidToken, err)
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return fmt.Errorf("failed to parse token: %w: %w: %w", ErrInvalidIssuer, ErrInvalidToken, err)
 	}
+	return fmt.Errorf("failed to parse token: %w: %w", ErrInvalidToken, err)
+}
 
-	claims, ok := token.Claims.(*Claims)
-	if !ok {
-		return nil, fmt.Errorf("invalid token claims")
+// parserOptions returns the claim checks of the parser: a required "exp",
+// "iat" not in the future, the service's issuer, and the leeway.
+func (s Service) parserOptions() []jwt.ParserOption {
+	opts := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(s.validation.Leeway),
+	}
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+	return opts
+}
+
+// checkClaims runs the checks the parser has no option for: any of several
+// audiences, and the maximum token age.
+func (s Service) checkClaims(claims *jwt.RegisteredClaims) error {
+	if len(s.validation.Audience) > 0 && !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(s.validation.Audience, aud)
+	}) {
+		return fmt.Errorf("audience %v: %w: %w", []string(claims.Audience), ErrInvalidAudience, ErrInvalidToken)
 	}
 
-	return claims, nil
+	if s.validation.MaxAge > 0 {
+		if claims.IssuedAt == nil {
+			return fmt.Errorf("token without iat with a max age: %w", ErrInvalidToken)
+		}
+		if time.Since(claims.IssuedAt.Time) > s.validation.MaxAge+s.validation.Leeway {
+			return fmt.Errorf("token issued at %s is older than %s: %w: %w", claims.IssuedAt.Time.Format(time.RFC3339), s.validation.MaxAge, ErrTokenExpired, ErrInvalidToken)
+		}
+	}
+	return nil
+}
+
+// verificationKey returns the key for the token's "kid" header. The token
+// must use the key's algorithm, so a public key can't be used as an HMAC
+// secret.
+func (s Service) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys.VerificationKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id [%s]", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
 }
 
+// RefreshToken re-signs an access token within 5 minutes of its expiry.
+//
+// Deprecated: an access token that renews itself can be renewed forever
+// once stolen. Use IssueTokens and Refresh with a RefreshStore instead.
 func (s Service) RefreshToken(tokenString string) (string, error) {
 	claims, err := s.ValidateToken(tokenString)
 	if err != nil {
@@ -92,5 +325,5 @@ func (s Service) RefreshToken(tokenString string) (string, error) {
 	}
 
 	// Generate new token
-	return s.GenerateToken(claims.UserID, claims.Email, claims.AccountType)
+	return s.GenerateScopedToken(claims.UserID, claims.Email, claims.AccountType, claims.Scopes()...)
 }
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -29,6 +31,45 @@ func TestNewServiceFromConfig(t *testing.T) {
 	if svc.expiry != 2*time.Hour {
 		t.Errorf("expected expiry 2h, got %v", svc.expiry)
 	}
+
+	// An empty HS256 secret was always accepted
+	if svc := NewServiceFromConfig(Config{Issuer: "test-issuer"}); svc.keys == nil {
+		t.Error("expected a service for an empty secret key")
+	}
+
+	// Config errors are logged and leave the defaults in place
+	svc = NewServiceFromConfig(Config{SecretKey: "test-secret", Expiry: "soon", RefreshExpiry: "later", Leeway: "1x"})
+	if svc.expiry != 24*time.Hour || svc.refreshExpiry != defaultRefreshExpiry || svc.validation.Leeway != 0 {
+		t.Errorf("expected default durations, got expiry %v, refresh expiry %v, leeway %v", svc.expiry, svc.refreshExpiry, svc.validation.Leeway)
+	}
+
+	// A missing asymmetric key leaves a service that can't sign tokens
+	svc = NewServiceFromConfig(Config{Algorithm: AlgRS256})
+	if _, err := svc.GenerateToken("user-1", "user@example.com", "admin"); err == nil {
+		t.Error("expected error signing without a key")
+	}
+}
+
+func TestLoadService(t *testing.T) {
+	if _, err := LoadService(Config{SecretKey: "test-secret", Expiry: "1h"}); err != nil {
+		t.Fatalf("failed to load service: %v", err)
+	}
+	if _, err := LoadService(Config{}); err == nil {
+		t.Error("expected error for an empty secret key")
+	}
+	if _, err := LoadService(Config{Algorithm: AlgRS256}); err == nil {
+		t.Error("expected error for a missing RS256 key")
+	}
+
+	_, err := LoadService(Config{SecretKey: "test-secret", Expiry: "soon", RefreshExpiry: "later", MaxAge: "old"})
+	if err == nil {
+		t.Fatal("expected error for invalid durations")
+	}
+	for _, name := range []string{"expiry [soon]", "refresh expiry [later]", "max age [old]"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to report %s, got: %v", name, err)
+		}
+	}
 }
 
 func TestGenerateAndValidateToken(t *testing.T) {
@@ -68,6 +109,23 @@ func TestValidateToken_Invalid(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for invalid token")
 	}
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestValidateToken_Expired(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "-1m")
+
+	token, err := svc.GenerateToken("user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	_, err = svc.ValidateToken(token)
+	if !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected ErrTokenExpired, got %v", err)
+	}
 }
 
 func TestValidateToken_WrongSecret(t *testing.T) {
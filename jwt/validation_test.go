@@ -0,0 +1,157 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidateToken_Options(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "1h").WithValidation(ValidationOptions{
+		Audience: []string{"orders", "billing"},
+		Leeway:   30 * time.Second,
+		MaxAge:   2 * time.Hour,
+	})
+	key, _ := svc.keys.SigningKey()
+
+	now := time.Now()
+	claims := func(edit func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss": "test-issuer",
+			"aud": []string{"billing"},
+			"sub": "user-1",
+			"iat": now.Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+
+	issued, err := svc.GenerateToken("user-1", "test@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	parsed, err := svc.ValidateToken(issued)
+	if err != nil {
+		t.Fatalf("failed to validate issued token: %v", err)
+	}
+	if len(parsed.Audience) != 2 {
+		t.Errorf("expected issued tokens to carry the audience, got %v", parsed.Audience)
+	}
+
+	other := NewService("other-secret", "test-issuer", "1h")
+	forged, _ := other.GenerateToken("user-1", "test@example.com", "user")
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "valid", token: signRemoteToken(t, key, claims(nil))},
+		{name: "expired within leeway", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-10 * time.Second).Unix()
+		}))},
+		{name: "expired", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-time.Minute).Unix()
+		})), want: ErrTokenExpired},
+		{name: "not valid yet", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			c["nbf"] = now.Add(time.Minute).Unix()
+		})), want: ErrTokenNotValidYet},
+		{name: "issued in the future", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			c["iat"] = now.Add(time.Minute).Unix()
+		})), want: ErrTokenNotValidYet},
+		{name: "too old", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			c["iat"] = now.Add(-3 * time.Hour).Unix()
+		})), want: ErrTokenExpired},
+		{name: "wrong audience", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			c["aud"] = "admin"
+		})), want: ErrInvalidAudience},
+		{name: "no audience", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			delete(c, "aud")
+		})), want: ErrInvalidAudience},
+		{name: "wrong issuer", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			c["iss"] = "other-issuer"
+		})), want: ErrInvalidIssuer},
+		{name: "no expiry", token: signRemoteToken(t, key, claims(func(c jwt.MapClaims) {
+			delete(c, "exp")
+		})), want: ErrInvalidToken},
+		{name: "bad signature", token: forged, want: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ValidateToken(tt.token)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("expected token to be valid, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected %v to also match ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestLoadService_Validation(t *testing.T) {
+	svc, err := LoadService(Config{
+		SecretKey: "test-secret",
+		Issuer:    "test-issuer",
+		Expiry:    "1h",
+		Audience:  []string{"orders"},
+		Leeway:    "5s",
+		MaxAge:    "12h",
+	})
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	if svc.validation.Leeway != 5*time.Second || svc.validation.MaxAge != 12*time.Hour || len(svc.validation.Audience) != 1 {
+		t.Errorf("unexpected validation options %+v", svc.validation)
+	}
+
+	if _, err := LoadService(Config{SecretKey: "test-secret", Leeway: "soon"}); err == nil {
+		t.Error("expected error for invalid leeway")
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	svc := NewService("test-secret", "test-issuer", "1h")
+	h := Authenticate(svc, AuthConfig{Realm: "api"})(
+		Require("api", RequireScopes("orders:read", "orders:write"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+	)
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   int
+	}{
+		{name: "all scopes", scopes: []string{"orders:read", "orders:write", "billing:read"}, want: http.StatusOK},
+		{name: "missing scope", scopes: []string{"orders:read"}, want: http.StatusForbidden},
+		{name: "no scopes", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := svc.GenerateScopedToken("user-1", "test@example.com", "user", tt.scopes...)
+			if err != nil {
+				t.Fatalf("failed to generate token: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
@@ -30,6 +30,7 @@ type Config struct {
 
 	// Monitoring settings
 	DatabaseEnableMetrics bool `conf:"env:DATABASE_ENABLE_METRICS,default:true"`
+	DatabaseEnableTracing bool `conf:"env:DATABASE_ENABLE_TRACING,default:true"`
 }
 
 // DefaultConfig returns a production-optimized database configuration.
@@ -61,6 +62,7 @@ func DefaultConfig() Config {
 		DatabaseConnectTimeout:         30 * time.Second,
 		DatabaseStatementCacheCapacity: 512,
 		DatabaseEnableMetrics:          true,
+		DatabaseEnableTracing:          true,
 	}
 }
 
@@ -35,12 +35,9 @@ type InstrumentedTx struct {
 
 // Query executes a query with instrumentation
 func (c *InstrumentedConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
-	start := time.Now()
+	ctx, done := c.middleware.pool.startQuery(ctx, sql)
 	rows, err := c.Conn.Query(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	c.middleware.pool.RecordQuery(operation, table, duration, err)
+	done(err)
 
 	return rows, err
 }
@@ -49,24 +46,18 @@ func (c *InstrumentedConn) Query(ctx context.Context, sql string, args ...interf
 // Note: QueryRow errors are not captured until Scan() is called on the returned Row.
 // Only query execution time is recorded here; scan errors must be handled separately.
 func (c *InstrumentedConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	start := time.Now()
+	ctx, done := c.middleware.pool.startQuery(ctx, sql)
 	row := c.Conn.QueryRow(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	c.middleware.pool.RecordQuery(operation, table, duration, nil)
+	done(nil)
 
 	return row
 }
 
 // Exec executes a query with instrumentation
 func (c *InstrumentedConn) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
-	start := time.Now()
+	ctx, done := c.middleware.pool.startQuery(ctx, sql)
 	result, err := c.Conn.Exec(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	c.middleware.pool.RecordQuery(operation, table, duration, err)
+	done(err)
 
 	return result, err
 }
@@ -87,12 +78,9 @@ func (c *InstrumentedConn) Begin(ctx context.Context) (pgx.Tx, error) {
 
 // Query executes a query within a transaction with instrumentation
 func (tx *InstrumentedTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
-	start := time.Now()
+	ctx, done := tx.middleware.pool.startQuery(ctx, sql)
 	rows, err := tx.Tx.Query(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	tx.middleware.pool.RecordQuery(operation, table, duration, err)
+	done(err)
 
 	return rows, err
 }
@@ -101,24 +89,18 @@ func (tx *InstrumentedTx) Query(ctx context.Context, sql string, args ...interfa
 // Note: QueryRow errors are not captured until Scan() is called on the returned Row.
 // Only query execution time is recorded here; scan errors must be handled separately.
 func (tx *InstrumentedTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	start := time.Now()
+	ctx, done := tx.middleware.pool.startQuery(ctx, sql)
 	row := tx.Tx.QueryRow(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	tx.middleware.pool.RecordQuery(operation, table, duration, nil)
+	done(nil)
 
 	return row
 }
 
 // Exec executes a query within a transaction with instrumentation
 func (tx *InstrumentedTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
-	start := time.Now()
+	ctx, done := tx.middleware.pool.startQuery(ctx, sql)
 	result, err := tx.Tx.Exec(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	tx.middleware.pool.RecordQuery(operation, table, duration, err)
+	done(err)
 
 	return result, err
 }
@@ -159,12 +141,9 @@ func NewQueryExecutor(pool *DatabasePool) *QueryExecutor {
 
 // QueryWithInstrumentation executes a query with automatic instrumentation
 func (qe *QueryExecutor) QueryWithInstrumentation(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
-	start := time.Now()
+	ctx, done := qe.pool.startQuery(ctx, sql)
 	rows, err := qe.pool.Query(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	qe.pool.RecordQuery(operation, table, duration, err)
+	done(err)
 
 	return rows, err
 }
@@ -173,24 +152,18 @@ func (qe *QueryExecutor) QueryWithInstrumentation(ctx context.Context, sql strin
 // Note: QueryRow errors are not captured until Scan() is called on the returned Row.
 // Only query execution time is recorded here; scan errors must be handled separately.
 func (qe *QueryExecutor) QueryRowWithInstrumentation(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	start := time.Now()
+	ctx, done := qe.pool.startQuery(ctx, sql)
 	row := qe.pool.QueryRow(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	qe.pool.RecordQuery(operation, table, duration, nil)
+	done(nil)
 
 	return row
 }
 
 // ExecWithInstrumentation executes a query with automatic instrumentation
 func (qe *QueryExecutor) ExecWithInstrumentation(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
-	start := time.Now()
+	ctx, done := qe.pool.startQuery(ctx, sql)
 	result, err := qe.pool.Exec(ctx, sql, args...)
-	duration := time.Since(start)
-
-	operation, table := parseSQL(sql)
-	qe.pool.RecordQuery(operation, table, duration, err)
+	done(err)
 
 	return result, err
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ardanlabs/conf/v3"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DatabasePool represents an enhanced PostgreSQL connection pool with monitoring
@@ -16,6 +17,8 @@ type DatabasePool struct {
 	config  Config
 	metrics *DatabaseMetrics
 	logger  *slog.Logger
+
+	tracerProvider trace.TracerProvider
 }
 
 // NewOptimized creates a new optimized PostgreSQL connection pool with monitoring
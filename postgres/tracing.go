@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/guilhermebr/gox/postgres"
+
+// SetTracerProvider sets the provider used for query spans. By default the
+// global provider is used, which records nothing until an SDK is installed.
+func (db *DatabasePool) SetTracerProvider(tp trace.TracerProvider) {
+	db.tracerProvider = tp
+}
+
+func (db *DatabasePool) tracer() trace.Tracer {
+	if db.tracerProvider != nil {
+		return db.tracerProvider.Tracer(tracerName)
+	}
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// startQuery starts a client span for sql, tagged with the operation and
+// table from parseSQL, and returns a function that ends it and records the
+// query metrics.
+func (db *DatabasePool) startQuery(ctx context.Context, sql string) (context.Context, func(error)) {
+	operation, table := parseSQL(sql)
+	start := time.Now()
+
+	var span trace.Span
+	if db.config.DatabaseEnableTracing {
+		ctx, span = db.tracer().Start(ctx, operation+" "+table,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNamePostgreSQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(table),
+				semconv.DBNamespace(db.config.DatabaseName),
+			),
+		)
+	}
+
+	return ctx, func(err error) {
+		db.RecordQuery(operation, table, time.Since(start), err)
+
+		if span == nil {
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
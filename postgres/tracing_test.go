@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeTx records the context of the last statement.
+type fakeTx struct {
+	pgx.Tx
+	ctx context.Context
+	err error
+}
+
+func (f *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	f.ctx = ctx
+	return pgconn.CommandTag{}, f.err
+}
+
+func TestInstrumentedTx_Spans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	pool := &DatabasePool{config: Config{DatabaseName: "app", DatabaseEnableTracing: true}}
+	pool.SetTracerProvider(tp)
+
+	fake := &fakeTx{}
+	tx := &InstrumentedTx{Tx: fake, middleware: NewQueryMiddleware(pool)}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	if _, err := tx.Exec(ctx, "UPDATE accounts SET balance = $1 WHERE id = $2", 10, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fake.err = errors.New("deadlock detected")
+	if _, err := tx.Exec(ctx, "DELETE FROM sessions WHERE id = $1", 1); err == nil {
+		t.Fatal("expected error")
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	update := spans[0]
+	if update.Name != "update accounts" {
+		t.Errorf("expected span name 'update accounts', got %q", update.Name)
+	}
+	if update.SpanKind != trace.SpanKindClient {
+		t.Errorf("expected client span, got %v", update.SpanKind)
+	}
+	if update.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected query span to be a child of the request span")
+	}
+
+	attrs := make(map[string]string)
+	for _, kv := range update.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	want := map[string]string{
+		"db.system.name":     "postgresql",
+		"db.operation.name":  "update",
+		"db.collection.name": "accounts",
+		"db.namespace":       "app",
+	}
+	for k, v := range want {
+		if attrs[k] != v {
+			t.Errorf("expected attribute %s=%s, got %q", k, v, attrs[k])
+		}
+	}
+
+	failed := spans[1]
+	if failed.Status.Code != codes.Error {
+		t.Errorf("expected error status, got %v", failed.Status.Code)
+	}
+	if len(failed.Events) == 0 {
+		t.Error("expected the error to be recorded on the span")
+	}
+	if trace.SpanContextFromContext(fake.ctx).SpanID() != failed.SpanContext.SpanID() {
+		t.Error("expected the statement to run in the span's context")
+	}
+}
+
+func TestInstrumentedTx_TracingDisabled(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	pool := &DatabasePool{}
+	pool.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	tx := &InstrumentedTx{Tx: &fakeTx{}, middleware: NewQueryMiddleware(pool)}
+	if _, err := tx.Exec(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(exporter.GetSpans()) != 0 {
+		t.Errorf("expected no spans with tracing disabled, got %d", len(exporter.GetSpans()))
+	}
+}
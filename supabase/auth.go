@@ -15,7 +15,10 @@ var ErrNilClient = errors.New("supabase client is nil")
 // SignUpWithEmail registers a new user with email and password.
 // metadata is optional user metadata attached to the account.
 // Returns the new user's ID.
-func SignUpWithEmail(_ context.Context, client *supabase.Client, email, password string, metadata map[string]interface{}) (string, error) {
+func SignUpWithEmail(ctx context.Context, client *supabase.Client, email, password string, metadata map[string]interface{}) (_ string, err error) {
+	_, span := startSpan(ctx, "sign_up")
+	defer func() { endSpan(span, err) }()
+
 	if client == nil {
 		return "", ErrNilClient
 	}
@@ -34,7 +37,10 @@ func SignUpWithEmail(_ context.Context, client *supabase.Client, email, password
 
 // SignInWithEmail authenticates a user with email and password.
 // Returns the access token from the resulting session.
-func SignInWithEmail(_ context.Context, client *supabase.Client, email, password string) (string, error) {
+func SignInWithEmail(ctx context.Context, client *supabase.Client, email, password string) (_ string, err error) {
+	_, span := startSpan(ctx, "sign_in")
+	defer func() { endSpan(span, err) }()
+
 	if client == nil {
 		return "", ErrNilClient
 	}
@@ -49,7 +55,10 @@ func SignInWithEmail(_ context.Context, client *supabase.Client, email, password
 
 // GetUserFromToken retrieves user information for the given access token.
 // It updates the client's auth session before fetching the user.
-func GetUserFromToken(_ context.Context, client *supabase.Client, token string) (*UserInfo, error) {
+func GetUserFromToken(ctx context.Context, client *supabase.Client, token string) (_ *UserInfo, err error) {
+	_, span := startSpan(ctx, "get_user")
+	defer func() { endSpan(span, err) }()
+
 	if client == nil {
 		return nil, ErrNilClient
 	}
@@ -66,7 +75,10 @@ func GetUserFromToken(_ context.Context, client *supabase.Client, token string)
 
 // AdminDeleteUser deletes a user by ID. Requires a client initialised with a
 // service-role key.
-func AdminDeleteUser(_ context.Context, client *supabase.Client, userID string) error {
+func AdminDeleteUser(ctx context.Context, client *supabase.Client, userID string) (err error) {
+	_, span := startSpan(ctx, "admin_delete_user")
+	defer func() { endSpan(span, err) }()
+
 	if client == nil {
 		return ErrNilClient
 	}
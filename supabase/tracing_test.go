@@ -0,0 +1,53 @@
+package supabase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAuthSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code":401,"msg":"invalid JWT"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewFromConfig(Config{URL: srv.URL, Key: "test-key"})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	if _, err := GetUserFromToken(context.Background(), client, "bad-token"); err == nil {
+		t.Fatal("expected error for rejected token")
+	}
+	if _, err := SignInWithEmail(context.Background(), nil, "a@b.com", "pass"); err != ErrNilClient {
+		t.Fatalf("expected ErrNilClient, got %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	for i, name := range []string{"supabase.auth.get_user", "supabase.auth.sign_in"} {
+		if spans[i].Name != name {
+			t.Errorf("expected span %q, got %q", name, spans[i].Name)
+		}
+		if spans[i].Status.Code != codes.Error {
+			t.Errorf("expected error status on %s, got %v", name, spans[i].Status.Code)
+		}
+	}
+}